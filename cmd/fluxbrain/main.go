@@ -0,0 +1,185 @@
+// Command fluxbrain collects FluxCD failure facts and forwards them to analyzers and notifiers.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/afeldman/fluxbrain/internal/api"
+	"github.com/afeldman/fluxbrain/internal/collector"
+	"github.com/afeldman/fluxbrain/internal/config"
+	"github.com/afeldman/fluxbrain/internal/logging"
+	"github.com/afeldman/fluxbrain/internal/metrics"
+	"github.com/afeldman/fluxbrain/internal/reconcile"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Set via -ldflags "-X main.version=... -X main.commit=..." at release time.
+var (
+	version = "dev"
+	commit  = "none"
+)
+
+const usage = `Usage: fluxbrain [command]
+
+Commands:
+  run         start the continuous reconciliation loop
+  once        execute a single reconciliation cycle and exit
+  version     print version information
+  failures    list open failures and their fingerprints
+  silences    list active silences
+  silence     snooze or acknowledge a fingerprint
+  unsilence   remove the silence of a fingerprint
+
+failures, silences, silence and unsilence talk to a running fluxbrain
+(-server, $FLUXBRAIN_API_URL, default http://localhost:8082). With -direct
+they use the state backend configured by FLUXBRAIN_* instead, e.g. in once
+mode where no fluxbrain serves the API.
+
+Without a command, FLUXBRAIN_RUN_MODE selects between run (continuous) and once.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "version":
+		fmt.Fprintf(stdout, "fluxbrain %s (commit %s)\n", version, commit)
+		return 0
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	case "failures", "silences", "silence", "unsilence":
+		return runAPICommand(command, args[1:], stdout, stderr)
+	case "", "run", "once":
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return 1
+	}
+	logger, err := logging.New(stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(stderr, "config error: %v\n", err)
+		return 1
+	}
+	slog.SetDefault(logger)
+	switch command {
+	case "run":
+		cfg.RunMode = config.RunModeContinuous
+	case "once":
+		cfg.RunMode = config.RunModeOnce
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a, err := newApp(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "setup error: %v\n", err)
+		return 1
+	}
+	defer a.close()
+
+	slog.Info("fluxbrain starting", "version", version, "cluster", cfg.ClusterName, "mode", cfg.RunMode)
+
+	if cfg.RunMode == config.RunModeOnce {
+		if err := a.engine.RunOnce(ctx); err != nil {
+			slog.Error("reconciliation failed", "error", err)
+			return 1
+		}
+		return 0
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go serve(ctx, "metrics", cfg.MetricsAddr, mux)
+	}
+	if cfg.ProbeAddr != "" {
+		go serve(ctx, "probe", cfg.ProbeAddr, a.probe.Handler())
+	}
+	if cfg.APIAddr != "" {
+		server := &api.Server{Store: a.engine.State, Token: cfg.APIToken}
+		go serve(ctx, "api", cfg.APIAddr, server.Handler())
+	}
+
+	// followers keep the watcher cache warm but only the leader processes
+	sink := collector.ErrorSink(a.engine.Process)
+	if a.elector != nil {
+		sink = func(ctx context.Context, ec types.ErrorContext) {
+			if a.elector.IsLeader() {
+				a.engine.Process(ctx, ec)
+			}
+		}
+	}
+
+	watchErr := make(chan error, 1)
+	if a.watcher != nil {
+		go func() {
+			watchErr <- a.watcher.Run(ctx, sink)
+			cancel()
+		}()
+	}
+
+	runner := reconcile.NewRunner(a.engine, cfg.RequeueInterval)
+	runner.OnCycle = a.probe.CycleCompleted
+	if a.elector != nil {
+		err = a.elector.Run(ctx, func(ctx context.Context) error {
+			if a.watcher != nil {
+				a.watcher.Replay() // events seen as follower were dropped
+			}
+			return runner.Start(ctx)
+		})
+	} else {
+		err = runner.Start(ctx)
+	}
+	cancel()
+	if a.watcher != nil {
+		if werr := <-watchErr; werr != nil && !errors.Is(werr, context.Canceled) {
+			err = werr
+		}
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		slog.Error("reconciliation loop failed", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// serve runs an HTTP server on addr until ctx is canceled.
+func serve(ctx context.Context, name, addr string, handler http.Handler) {
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	slog.Info("server listening", "server", name, "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("server failed", "server", name, "error", err)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/afeldman/fluxbrain/internal/api"
+	"github.com/afeldman/fluxbrain/internal/config"
+	"github.com/afeldman/fluxbrain/internal/state"
+)
+
+const defaultAPIURL = "http://localhost:8082"
+
+// runAPICommand runs the failures, silences, silence and unsilence commands
+// against a running fluxbrain's API or, with -direct, the configured state
+// backend.
+func runAPICommand(command string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", getenvDefault("FLUXBRAIN_API_URL", defaultAPIURL), "fluxbrain API URL")
+	direct := fs.Bool("direct", false, "use the state backend configured by FLUXBRAIN_* instead of the API")
+	var (
+		snooze                       time.Duration
+		until, revision, reason      string
+		untilResolved, untilRevision bool
+	)
+	if command == "silence" {
+		fs.DurationVar(&snooze, "for", 0, "snooze for this long")
+		fs.StringVar(&until, "until", "", "snooze until this RFC 3339 time")
+		fs.BoolVar(&untilResolved, "until-resolved", false, "acknowledge until the failure resolves")
+		fs.BoolVar(&untilRevision, "until-revision-change", false, "acknowledge until the Git revision changes")
+		fs.StringVar(&revision, "revision", "", "revision to acknowledge (default: revision of the open failure)")
+		fs.StringVar(&reason, "reason", "", "why the fingerprint is silenced")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: fluxbrain %s [flags]%s\n", command, apiCommandArgs(command))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	var client api.Operations = &api.Client{BaseURL: *server, Token: os.Getenv("FLUXBRAIN_API_TOKEN")}
+	if *direct {
+		store, err := directStore()
+		if err != nil {
+			fmt.Fprintf(stderr, "%s failed: %v\n", command, err)
+			return 1
+		}
+		if c, ok := store.(io.Closer); ok {
+			defer c.Close()
+		}
+		client = (&api.Server{Store: store}).Direct()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var err error
+	switch command {
+	case "failures":
+		err = printFailures(ctx, client, stdout)
+	case "silences":
+		err = printSilences(ctx, client, stdout)
+	case "silence", "unsilence":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+		fp := fs.Arg(0)
+		if command == "unsilence" {
+			err = client.Unsilence(ctx, fp)
+			break
+		}
+		var req api.SilenceRequest
+		req, err = silenceRequest(snooze, until, untilResolved, untilRevision)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 2
+		}
+		req.Revision, req.Reason = revision, reason
+		var entry api.SilenceEntry
+		entry, err = client.Silence(ctx, fp, req)
+		if err == nil {
+			fmt.Fprintf(stdout, "silenced %s (%s)\n", entry.Fingerprint, describeSilence(entry.Silence))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s failed: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+func apiCommandArgs(command string) string {
+	switch command {
+	case "silence":
+		return " <fingerprint>\n\nExactly one of -for, -until, -until-resolved and -until-revision-change is required."
+	case "unsilence":
+		return " <fingerprint>"
+	}
+	return ""
+}
+
+// silenceRequest maps exactly one mode flag to a request.
+func silenceRequest(snooze time.Duration, until string, untilResolved, untilRevision bool) (api.SilenceRequest, error) {
+	var modes []api.SilenceRequest
+	if snooze > 0 {
+		modes = append(modes, api.SilenceRequest{Mode: state.SilenceSnooze, Duration: snooze.String()})
+	}
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return api.SilenceRequest{}, fmt.Errorf("invalid -until: %w", err)
+		}
+		modes = append(modes, api.SilenceRequest{Mode: state.SilenceSnooze, Until: t})
+	}
+	if untilResolved {
+		modes = append(modes, api.SilenceRequest{Mode: state.SilenceResolved})
+	}
+	if untilRevision {
+		modes = append(modes, api.SilenceRequest{Mode: state.SilenceRevision})
+	}
+	if len(modes) != 1 {
+		return api.SilenceRequest{}, errors.New("pass exactly one of -for, -until, -until-resolved and -until-revision-change")
+	}
+	return modes[0], nil
+}
+
+func printFailures(ctx context.Context, client api.Operations, out io.Writer) error {
+	failures, err := client.Failures(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FINGERPRINT\tKIND\tNAMESPACE\tNAME\tREASON\tREVISION\tNOTIFIED")
+	for _, f := range failures {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%t\n", f.Fingerprint, f.Resource.Kind, f.Resource.Namespace, f.Resource.Name, f.Reason, f.Revision, f.Notified)
+	}
+	return w.Flush()
+}
+
+func printSilences(ctx context.Context, client api.Operations, out io.Writer) error {
+	silences, err := client.Silences(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FINGERPRINT\tSILENCE\tREASON")
+	for _, s := range silences {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Fingerprint, describeSilence(s.Silence), s.Reason)
+	}
+	return w.Flush()
+}
+
+func describeSilence(s state.Silence) string {
+	switch s.Mode {
+	case state.SilenceSnooze:
+		return "snoozed until " + s.Until.Format(time.RFC3339)
+	case state.SilenceRevision:
+		return "acknowledged until revision " + s.Revision + " changes"
+	}
+	return "acknowledged until resolved"
+}
+
+// directStore opens the state backend of the fluxbrain configured by the environment.
+func directStore() (state.Store, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+	return openStore(cfg)
+}
+
+func getenvDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
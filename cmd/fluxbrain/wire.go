@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/afeldman/fluxbrain/internal/analysis"
+	"github.com/afeldman/fluxbrain/internal/collector"
+	"github.com/afeldman/fluxbrain/internal/config"
+	"github.com/afeldman/fluxbrain/internal/health"
+	"github.com/afeldman/fluxbrain/internal/kube"
+	"github.com/afeldman/fluxbrain/internal/leader"
+	"github.com/afeldman/fluxbrain/internal/notify"
+	"github.com/afeldman/fluxbrain/internal/reconcile"
+	"github.com/afeldman/fluxbrain/internal/rules"
+	"github.com/afeldman/fluxbrain/internal/state"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// app bundles the wired components of a fluxbrain process.
+type app struct {
+	engine *reconcile.Engine
+	// watcher is set in continuous mode when events are watched instead of listed.
+	watcher *collector.FluxEventWatcher
+	// probe backs /healthz and /readyz in continuous mode.
+	probe *health.Probe
+	// elector is set when leader election is enabled; only the leader notifies.
+	elector *leader.Elector
+}
+
+// newApp wires collectors, analyzer, notifiers and state into a reconcile.Engine.
+func newApp(cfg config.Config) (*app, error) {
+	restCfg, err := kube.RESTConfig(cfg.Kubeconfig, cfg.KubeContext)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create dynamic client: %w", err)
+	}
+
+	ruleSet, err := rules.Load(cfg.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &app{}
+	// one event list per namespace and cycle, shared by all collectors
+	lister := collector.NewEventCache(collector.NewKubernetesEventLister(clientset, collector.EventAPI(cfg.EventAPI)))
+	var logs *collector.LogCollector
+	if cfg.CollectControllerLogs {
+		logs = collector.NewLogCollector(clientset, cfg.FluxNamespace)
+		logs.Window = cfg.ControllerLogWindow
+	}
+	var collectors []reconcile.ErrorCollector
+	switch {
+	case cfg.StatusCollector:
+		status := collector.NewStatusCollector(cfg.ClusterName, dynamicClient, lister)
+		status.Logs = logs
+		sc := collector.NewStatusErrorCollector(status, cfg.FluxNamespace, types.FluxResourceKindKustomization)
+		sc.Rules = ruleSet
+		collectors = append(collectors, sc)
+	case cfg.RunMode == config.RunModeContinuous && cfg.WatchEvents:
+		a.watcher = collector.NewFluxEventWatcher(cfg.ClusterName, cfg.FluxNamespace, clientset)
+		a.watcher.Rules = ruleSet
+		a.watcher.Logs = logs
+	default:
+		ec := collector.NewFluxErrorCollector(cfg.ClusterName, cfg.FluxNamespace, lister)
+		ec.Rules = ruleSet
+		ec.Logs = logs
+		collectors = append(collectors, ec)
+	}
+
+	hr := collector.NewHelmReleaseCollector(cfg.ClusterName, cfg.FluxNamespace, dynamicClient, lister)
+	hr.Rules = ruleSet
+	hr.Logs = logs
+	collectors = append(collectors, hr)
+	for _, sc := range collector.NewSourceCollectors(cfg.ClusterName, cfg.FluxNamespace, dynamicClient, lister) {
+		sc.Rules = ruleSet
+		sc.Logs = logs
+		collectors = append(collectors, sc)
+	}
+
+	backoff, reminders, err := loadPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	store, err := newStore(cfg, clientset, backoff, reminders.Retention())
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := state.ParseFingerprintOptions(cfg.Fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FLUXBRAIN_FINGERPRINT: %w", err)
+	}
+	strictness, err := reconcile.ParseStrictness(cfg.Strictness)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FLUXBRAIN_STRICTNESS: %w", err)
+	}
+	a.engine = reconcile.NewEngine(
+		collectors,
+		analysis.NewMockAnalyzer(),
+		newNotifiers(cfg),
+		store,
+	)
+	a.engine.Caches = []reconcile.CycleCache{lister}
+	a.engine.Readiness = collector.NewStatusCollector(cfg.ClusterName, dynamicClient, nil)
+	a.engine.Concurrency = cfg.Concurrency
+	a.engine.ItemTimeout = cfg.ItemTimeout
+	a.engine.NotifyTimeout = cfg.NotifyTimeout
+	a.engine.Strictness = strictness
+	a.engine.ReportPath = cfg.ReportFile
+	a.engine.Fingerprinter = state.NewFingerprinter(fingerprint)
+	a.engine.Reminders = reminders
+	a.engine.Flapping = state.FlapPolicy{Window: cfg.FlapWindow, Threshold: cfg.FlapThreshold}
+
+	a.probe = health.NewProbe(cfg.RequeueInterval, cfg.LivenessMissedCycles)
+	a.probe.AddCheck("apiserver", health.APIServer(clientset.Discovery().RESTClient()))
+	if p, ok := a.engine.State.(state.Pinger); ok {
+		a.probe.AddCheck("state", p.Ping)
+	}
+
+	if cfg.LeaderElection && cfg.RunMode == config.RunModeContinuous {
+		identity, err := leader.DefaultIdentity()
+		if err != nil {
+			return nil, err
+		}
+		a.elector = leader.NewElector(clientset, cfg.LeaderElectionNamespace, cfg.LeaseName, identity)
+		a.elector.LeaseDuration = cfg.LeaseDuration
+		a.elector.RenewDeadline = cfg.LeaseRenewDeadline
+		a.elector.RetryPeriod = cfg.LeaseRetryPeriod
+		a.probe.Standby = func() bool { return !a.elector.IsLeader() }
+	}
+	return a, nil
+}
+
+// loadPolicies returns the configured backoff and reminder policies.
+func loadPolicies(cfg config.Config) (*state.BackoffPolicies, state.ReminderPolicy, error) {
+	backoff, err := state.LoadBackoffPolicies(cfg.BackoffFile, state.Backoff{
+		Strategy:   cfg.BackoffStrategy,
+		Base:       cfg.BackoffBase,
+		Max:        cfg.BackoffMax,
+		Multiplier: cfg.BackoffMultiplier,
+		Jitter:     cfg.BackoffJitter,
+	})
+	if err != nil {
+		return nil, state.ReminderPolicy{}, err
+	}
+	intervals, err := state.ParseReminderIntervals(cfg.ReminderIntervals)
+	if err != nil {
+		return nil, state.ReminderPolicy{}, fmt.Errorf("invalid FLUXBRAIN_REMINDER_INTERVALS: %w", err)
+	}
+	return backoff, state.ReminderPolicy{Intervals: intervals, EscalateAfter: cfg.ReminderEscalateAfter}, nil
+}
+
+// openStore opens the configured state backend outside a fluxbrain process,
+// for CLI commands with -direct. The memory backend has nothing to open.
+func openStore(cfg config.Config) (state.Store, error) {
+	if cfg.StateBackend == config.StateBackendMemory {
+		return nil, fmt.Errorf("FLUXBRAIN_STATE_BACKEND=%s keeps no state outside the fluxbrain process; use the API", cfg.StateBackend)
+	}
+	backoff, reminders, err := loadPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var clientset kubernetes.Interface
+	if cfg.StateBackend == config.StateBackendConfigMap {
+		restCfg, err := kube.RESTConfig(cfg.Kubeconfig, cfg.KubeContext)
+		if err != nil {
+			return nil, err
+		}
+		if clientset, err = kubernetes.NewForConfig(restCfg); err != nil {
+			return nil, fmt.Errorf("create kubernetes client: %w", err)
+		}
+	}
+	return newStore(cfg, clientset, backoff, reminders.Retention())
+}
+
+// newStore returns the configured backoff state backend.
+func newStore(cfg config.Config, clientset kubernetes.Interface, backoff *state.BackoffPolicies, notifyRetention time.Duration) (state.Store, error) {
+	switch cfg.StateBackend {
+	case config.StateBackendRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		store := state.NewRedisStore(client, 0, 0, cfg.RedisPrefix)
+		store.Timeout = cfg.RedisTimeout
+		store.Backoff = backoff
+		store.NotifyRetention = notifyRetention
+		store.HistoryRetention = cfg.HistoryRetention
+		return store, nil
+	case config.StateBackendFile:
+		store, err := state.OpenFileStore(cfg.StatePath, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		store.Retention = cfg.StateRetention
+		store.Backoff = backoff
+		store.NotifyRetention = notifyRetention
+		store.HistoryRetention = cfg.HistoryRetention
+		return store, nil
+	case config.StateBackendConfigMap:
+		store := state.NewConfigMapStore(clientset, cfg.FluxNamespace, cfg.StateConfigMap, 0, 0)
+		store.TTL = cfg.StateRetention
+		store.Backoff = backoff
+		store.NotifyRetention = notifyRetention
+		store.HistoryRetention = cfg.HistoryRetention
+		return store, nil
+	}
+	store := state.NewMemoryStore(0, 0)
+	store.Backoff = backoff
+	store.HistoryRetention = cfg.HistoryRetention
+	return store, nil
+}
+
+// close releases resources held by the state backend.
+func (a *app) close() {
+	if c, ok := a.engine.State.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			slog.Error("close state store", "error", err)
+		}
+	}
+}
+
+// newNotifiers returns a notifier for every channel that is configured.
+func newNotifiers(cfg config.Config) []types.Notifier {
+	var notifiers []types.Notifier
+	if cfg.NotificationSlackWebhook != "" {
+		notifiers = append(notifiers, notify.SlackNotifier{WebhookURL: cfg.NotificationSlackWebhook})
+	}
+	if cfg.NotificationWebhookURL != "" {
+		notifiers = append(notifiers, notify.WebhookNotifier{URL: cfg.NotificationWebhookURL})
+	}
+	if cfg.GitHubOwner != "" && cfg.GitHubRepo != "" && cfg.GitHubToken != "" {
+		notifiers = append(notifiers, notify.GitHubNotifier{
+			Owner: cfg.GitHubOwner,
+			Repo:  cfg.GitHubRepo,
+			Token: cfg.GitHubToken,
+		})
+	}
+	return notifiers
+}
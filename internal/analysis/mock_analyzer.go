@@ -0,0 +1,27 @@
+package analysis
+
+import (
+	"context"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// MockAnalyzer implements types.Analyzer without any analysis logic.
+// It only mirrors the observed facts until the errorbrain adapter replaces it.
+type MockAnalyzer struct{}
+
+// NewMockAnalyzer returns the placeholder analyzer.
+func NewMockAnalyzer() MockAnalyzer {
+	return MockAnalyzer{}
+}
+
+// Analyze copies the observed reason and message into an AnalysisResult.
+func (MockAnalyzer) Analyze(ctx context.Context, ec types.ErrorContext) (types.AnalysisResult, error) {
+	if err := ctx.Err(); err != nil {
+		return types.AnalysisResult{}, err
+	}
+	return types.AnalysisResult{
+		Summary:   ec.ErrorMsg,
+		RootCause: ec.Reason,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/afeldman/fluxbrain/internal/reconcile"
+)
+
+// Client calls a fluxbrain API server, e.g. through kubectl port-forward.
+type Client struct {
+	BaseURL string
+	Token   string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Failures lists the open failures known to the server behind BaseURL.
+func (c *Client) Failures(ctx context.Context) ([]reconcile.Failure, error) {
+	var out []reconcile.Failure
+	err := c.do(ctx, http.MethodGet, "/api/v1/failures", nil, &out)
+	return out, err
+}
+
+// Silences lists the silences that have not ended.
+func (c *Client) Silences(ctx context.Context) ([]SilenceEntry, error) {
+	var out []SilenceEntry
+	err := c.do(ctx, http.MethodGet, "/api/v1/silences", nil, &out)
+	return out, err
+}
+
+// Silence snoozes or acknowledges fp.
+func (c *Client) Silence(ctx context.Context, fp string, req SilenceRequest) (SilenceEntry, error) {
+	var out SilenceEntry
+	err := c.do(ctx, http.MethodPut, "/api/v1/silences/"+url.PathEscape(fp), req, &out)
+	return out, err
+}
+
+// Unsilence removes the silence of fp.
+func (c *Client) Unsilence(ctx context.Context, fp string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/silences/"+url.PathEscape(fp), nil, nil)
+}
+
+// do sends a request with an optional JSON body and decodes the response into out.
+func (c *Client) do(ctx context.Context, method, path string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e errorBody
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("fluxbrain api returned %d: %s", resp.StatusCode, e.Error)
+		}
+		return fmt.Errorf("fluxbrain api returned %d", resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+
+	"github.com/afeldman/fluxbrain/internal/reconcile"
+)
+
+// Operations are the failure and silence operations of the API. A Client
+// sends them to a server; Server.Direct applies them to the server's Store.
+type Operations interface {
+	Failures(ctx context.Context) ([]reconcile.Failure, error)
+	Silences(ctx context.Context) ([]SilenceEntry, error)
+	Silence(ctx context.Context, fp string, req SilenceRequest) (SilenceEntry, error)
+	Unsilence(ctx context.Context, fp string) error
+}
+
+// Direct returns Operations that act on the Store without HTTP, e.g. for the
+// CLI when no fluxbrain serves the API (once mode). Requests are validated
+// like the server's; Token is not checked.
+func (s *Server) Direct() Operations {
+	return direct{s}
+}
+
+type direct struct{ s *Server }
+
+func (d direct) Failures(ctx context.Context) ([]reconcile.Failure, error) {
+	return d.s.failures(ctx)
+}
+
+func (d direct) Silences(ctx context.Context) ([]SilenceEntry, error) {
+	return d.s.silences(ctx)
+}
+
+func (d direct) Silence(ctx context.Context, fp string, req SilenceRequest) (SilenceEntry, error) {
+	silence, err := d.s.silence(ctx, fp, req)
+	if err != nil {
+		return SilenceEntry{}, err
+	}
+	if err := d.s.Store.Silence(ctx, fp, silence); err != nil {
+		return SilenceEntry{}, err
+	}
+	return SilenceEntry{Fingerprint: fp, Silence: silence}, nil
+}
+
+func (d direct) Unsilence(ctx context.Context, fp string) error {
+	return d.s.Store.Unsilence(ctx, fp)
+}
@@ -0,0 +1,195 @@
+// Package api serves fluxbrain's operator API: open failures and silences.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/afeldman/fluxbrain/internal/reconcile"
+	"github.com/afeldman/fluxbrain/internal/state"
+)
+
+// SilenceRequest is the body of PUT /api/v1/silences/{fingerprint}.
+type SilenceRequest struct {
+	// Mode is state.SilenceSnooze, state.SilenceResolved or state.SilenceRevision.
+	Mode string `json:"mode"`
+	// Until or Duration end a snooze.
+	Until    time.Time `json:"until,omitempty"`
+	Duration string    `json:"duration,omitempty"`
+	// Revision is acknowledged in revision mode; empty takes the revision of
+	// the open failure.
+	Revision string `json:"revision,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// SilenceEntry is a stored silence as listed by GET /api/v1/silences.
+type SilenceEntry struct {
+	Fingerprint string `json:"fingerprint"`
+	state.Silence
+}
+
+// Server serves:
+//
+//	GET    /api/v1/failures                  open failures
+//	GET    /api/v1/silences                  silences that have not ended
+//	PUT    /api/v1/silences/{fingerprint}    snooze or acknowledge a fingerprint
+//	DELETE /api/v1/silences/{fingerprint}    remove a silence
+type Server struct {
+	Store state.Store
+	// Failures lists open failures; nil lists the ones stored in Store, so
+	// every replica sharing a state backend serves the leader's failures.
+	Failures func(ctx context.Context) ([]reconcile.Failure, error)
+	// Token, when set, is required as "Authorization: Bearer <token>".
+	Token string
+	// Now returns the current time; tests inject a fake clock.
+	Now func() time.Time
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/failures", s.listFailures)
+	mux.HandleFunc("GET /api/v1/silences", s.listSilences)
+	mux.HandleFunc("PUT /api/v1/silences/{fingerprint}", s.putSilence)
+	mux.HandleFunc("DELETE /api/v1/silences/{fingerprint}", s.deleteSilence)
+	return s.authorize(mux)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listFailures(w http.ResponseWriter, r *http.Request) {
+	failures, err := s.failures(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if failures == nil {
+		failures = []reconcile.Failure{}
+	}
+	writeJSON(w, http.StatusOK, failures)
+}
+
+func (s *Server) listSilences(w http.ResponseWriter, r *http.Request) {
+	silences, err := s.silences(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, silences)
+}
+
+func (s *Server) putSilence(w http.ResponseWriter, r *http.Request) {
+	fp := r.PathValue("fingerprint")
+	var req SilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decode request: %w", err))
+		return
+	}
+	silence, err := s.silence(r.Context(), fp, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.Store.Silence(r.Context(), fp, silence); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SilenceEntry{Fingerprint: fp, Silence: silence})
+}
+
+func (s *Server) deleteSilence(w http.ResponseWriter, r *http.Request) {
+	if err := s.Store.Unsilence(r.Context(), r.PathValue("fingerprint")); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// silence builds and validates the silence requested for fp.
+func (s *Server) silence(ctx context.Context, fp string, req SilenceRequest) (state.Silence, error) {
+	now := s.now()
+	silence := state.Silence{Mode: req.Mode, Until: req.Until, Revision: req.Revision, Reason: req.Reason, Created: now}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return silence, fmt.Errorf("invalid duration %q", req.Duration)
+		}
+		silence.Until = now.Add(d)
+	}
+	if silence.Mode == state.SilenceRevision && silence.Revision == "" {
+		failures, err := s.failures(ctx)
+		if err != nil {
+			return silence, fmt.Errorf("look up the revision of %s: %w", fp, err)
+		}
+		for _, f := range failures {
+			if f.Fingerprint == fp {
+				silence.Revision = f.Revision
+			}
+		}
+		if silence.Revision == "" {
+			return silence, fmt.Errorf("fingerprint %s is not open; pass the revision to acknowledge", fp)
+		}
+	}
+	return silence, silence.Validate()
+}
+
+// silences returns the stored silences ordered by fingerprint.
+func (s *Server) silences(ctx context.Context) ([]SilenceEntry, error) {
+	silences, err := s.Store.Silences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SilenceEntry, 0, len(silences))
+	for fp, silence := range silences {
+		out = append(out, SilenceEntry{Fingerprint: fp, Silence: silence})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Fingerprint < out[j].Fingerprint })
+	return out, nil
+}
+
+func (s *Server) failures(ctx context.Context) ([]reconcile.Failure, error) {
+	if s.Failures == nil {
+		return reconcile.LoadFailures(ctx, s.Store)
+	}
+	return s.Failures(ctx)
+}
+
+func (s *Server) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// errorBody is the JSON body of error responses.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afeldman/fluxbrain/internal/reconcile"
+	"github.com/afeldman/fluxbrain/internal/state"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+var testNow = time.Now().Truncate(time.Second)
+
+func newTestServer(t *testing.T, token string) (*Client, state.Store) {
+	t.Helper()
+	store := state.NewMemoryStore(0, 0)
+	ec := types.ErrorContext{
+		Resource: types.ResourceRef{Kind: types.FluxResourceKindKustomization, Namespace: "flux-system", Name: "apps"},
+		Git:      types.GitContext{Revision: "main@sha1:abc"},
+	}
+	if err := store.MarkOpen(context.Background(), "fp1", state.NewOpenFailure(ec, "flux", true)); err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		Store: store,
+		Token: token,
+		Now:   func() time.Time { return testNow },
+	}
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return &Client{BaseURL: ts.URL, Token: token}, store
+}
+
+func TestSilenceLifecycle(t *testing.T) {
+	client, store := newTestServer(t, "")
+	ctx := context.Background()
+
+	failures, err := client.Failures(ctx)
+	if err != nil || len(failures) != 1 || failures[0].Fingerprint != "fp1" {
+		t.Fatalf("Failures() = %+v, %v", failures, err)
+	}
+
+	entry, err := client.Silence(ctx, "fp1", SilenceRequest{Mode: state.SilenceRevision, Reason: "migration"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Revision != "main@sha1:abc" {
+		t.Errorf("revision should default to the open failure's, got %q", entry.Revision)
+	}
+
+	if _, err := client.Silence(ctx, "fp2", SilenceRequest{Mode: state.SilenceSnooze, Duration: "2h"}); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := store.SilenceOf(ctx, "fp2")
+	if s == nil || !s.Until.Equal(testNow.Add(2*time.Hour)) {
+		t.Errorf("snooze should end 2h after now, got %+v", s)
+	}
+
+	silences, err := client.Silences(ctx)
+	if err != nil || len(silences) != 2 || silences[0].Fingerprint != "fp1" {
+		t.Fatalf("Silences() = %+v, %v", silences, err)
+	}
+
+	if err := client.Unsilence(ctx, "fp1"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := store.SilenceOf(ctx, "fp1"); s != nil {
+		t.Errorf("silence should be removed, got %+v", s)
+	}
+}
+
+func TestSilenceRejectsInvalidRequests(t *testing.T) {
+	client, _ := newTestServer(t, "")
+	ctx := context.Background()
+
+	for _, req := range []SilenceRequest{
+		{Mode: "forever"},
+		{Mode: state.SilenceSnooze},
+		{Mode: state.SilenceSnooze, Duration: "-1h"},
+		{Mode: state.SilenceRevision}, // fp9 is not open, so its revision is unknown
+	} {
+		_, err := client.Silence(ctx, "fp9", req)
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Errorf("Silence(%+v) = %v, want a 400 error", req, err)
+		}
+	}
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	client, _ := newTestServer(t, "secret")
+	ctx := context.Background()
+
+	if _, err := client.Silences(ctx); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	client.Token = "wrong"
+	if _, err := client.Silences(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401, got %v", err)
+	}
+}
+
+func TestServerDirectUsesTheStore(t *testing.T) {
+	store := state.NewMemoryStore(0, 0)
+	server := &Server{
+		Store: store,
+		Failures: func(context.Context) ([]reconcile.Failure, error) {
+			return []reconcile.Failure{{Fingerprint: "fp1", Revision: "main@sha1:abc"}}, nil
+		},
+	}
+	ops := server.Direct()
+	ctx := context.Background()
+
+	entry, err := ops.Silence(ctx, "fp1", SilenceRequest{Mode: state.SilenceRevision})
+	if err != nil || entry.Revision != "main@sha1:abc" {
+		t.Fatalf("Silence() = %+v, %v", entry, err)
+	}
+	if s, _ := store.SilenceOf(ctx, "fp1"); s == nil || s.Mode != state.SilenceRevision {
+		t.Errorf("silence should be stored, got %+v", s)
+	}
+	if _, err := ops.Silence(ctx, "fp2", SilenceRequest{Mode: state.SilenceRevision}); err == nil {
+		t.Error("a revision silence of a fingerprint that is not open must be rejected")
+	}
+	if silences, err := ops.Silences(ctx); err != nil || len(silences) != 1 || silences[0].Fingerprint != "fp1" {
+		t.Errorf("Silences() = %+v, %v", silences, err)
+	}
+	if err := ops.Unsilence(ctx, "fp1"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := store.SilenceOf(ctx, "fp1"); s != nil {
+		t.Errorf("silence should be removed, got %+v", s)
+	}
+}
@@ -2,7 +2,14 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	fluxcontext "github.com/afeldman/fluxbrain/internal/context"
+	"github.com/afeldman/fluxbrain/internal/rules"
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
 
@@ -22,3 +29,101 @@ func NewFluxErrorCollector(cluster, namespace string, lister EventLister) *FluxE
 func (c *FluxErrorCollector) CollectErrors(ctx context.Context) ([]types.ErrorContext, error) {
 	return c.CollectFailedKustomizations(ctx)
 }
+
+// Name identifies the collector in metrics.
+func (c *FluxErrorCollector) Name() string { return "kustomization-events" }
+
+// CollectErrors implements the ErrorCollector interface.
+func (c *HelmReleaseCollector) CollectErrors(ctx context.Context) ([]types.ErrorContext, error) {
+	return c.CollectFailedHelmReleases(ctx)
+}
+
+// Name identifies the collector in metrics.
+func (c *HelmReleaseCollector) Name() string { return "helmrelease" }
+
+// CollectErrors implements the ErrorCollector interface.
+func (c *SourceCollector) CollectErrors(ctx context.Context) ([]types.ErrorContext, error) {
+	return c.CollectFailedSources(ctx)
+}
+
+// Name identifies the collector in metrics.
+func (c *SourceCollector) Name() string { return strings.ToLower(string(c.Kind)) }
+
+// StatusErrorCollector exposes a StatusCollector as an ErrorCollector.
+// It lists all objects of the configured kinds that are not Ready and builds
+// their ErrorContext through context.Builder.
+type StatusErrorCollector struct {
+	Status    *StatusCollector
+	Namespace string
+	Kinds     []types.FluxResourceKind
+	// Rules classifies failing conditions; nil uses rules.Default().
+	Rules *rules.Set
+}
+
+// NewStatusErrorCollector wraps a StatusCollector. Without kinds, all Flux kinds are listed.
+func NewStatusErrorCollector(status *StatusCollector, namespace string, kinds ...types.FluxResourceKind) *StatusErrorCollector {
+	if len(kinds) == 0 {
+		kinds = FluxKinds
+	}
+	return &StatusErrorCollector{
+		Status:    status,
+		Namespace: namespace,
+		Kinds:     kinds,
+	}
+}
+
+// Name identifies the collector in metrics.
+func (c *StatusErrorCollector) Name() string { return "status" }
+
+// CollectErrors implements the ErrorCollector interface.
+func (c *StatusErrorCollector) CollectErrors(ctx context.Context) ([]types.ErrorContext, error) {
+	if c.Status == nil || c.Status.Client == nil {
+		return nil, errors.New("status collector is not configured")
+	}
+
+	// one event list per cycle instead of one per object
+	status := *c.Status
+	if status.Lister != nil {
+		status.Lister = NewEventCache(status.Lister)
+	}
+	builder := fluxcontext.NewBuilder(&status)
+	ruleSet := ruleSetOr(c.Rules)
+
+	out := make([]types.ErrorContext, 0)
+	for _, kind := range c.Kinds {
+		gvr, ok := FluxKindGVR(kind)
+		if !ok {
+			return nil, fmt.Errorf("unsupported flux kind %q", kind)
+		}
+		items, err := listFluxObjects(ctx, status.Client, gvr, c.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			obj := &items[i]
+			conds := conditionsOf(obj, conditionReady)
+			if !isNotReady(conds) {
+				continue
+			}
+			ready, _ := findCondition(conds, conditionReady)
+			if !ruleSet.Match(conditionSignal(kind, obj, ready)) {
+				continue
+			}
+			ec, err := builder.Build(ctx, types.ResourceSelector{
+				Kind:      kind,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Cluster:   status.Cluster,
+			})
+			if apierrors.IsNotFound(err) {
+				continue // deleted since listing
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ec)
+		}
+	}
+	sortContexts(out)
+	return out, nil
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/afeldman/fluxbrain/internal/rules"
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
 
@@ -19,6 +20,7 @@ type K8sEvent struct {
 	Reason       string
 	Message      string
 	Type         string
+	Count        int32
 	Timestamp    time.Time
 	Source       string
 }
@@ -33,6 +35,10 @@ type FluxEventCollector struct {
 	Cluster   string
 	Namespace string
 	Lister    EventLister
+	// Rules classifies events as failures; nil uses rules.Default().
+	Rules *rules.Set
+	// Logs is optional; without it no controller logs are collected.
+	Logs *LogCollector
 }
 
 // NewFluxEventCollector constructs a collector for a specific cluster/namespace.
@@ -54,7 +60,22 @@ func (c *FluxEventCollector) CollectFailedKustomizations(ctx context.Context) ([
 	if err != nil {
 		return nil, err
 	}
+	out := c.contextsFromEvents(events)
+	for i := range out {
+		attachLogs(ctx, c.Logs, &out[i])
+	}
+	return out, nil
+}
+
+// contextsFromEvents groups failure events per Kustomization into ErrorContexts.
+func (c *FluxEventCollector) contextsFromEvents(events []K8sEvent) []types.ErrorContext {
+	// oldest first, so Reason is the first failure and ErrorMsg the latest one
+	events = append([]K8sEvent(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
 
+	ruleSet := ruleSetOr(c.Rules)
 	bucket := map[string]types.ErrorContext{}
 	for _, ev := range events {
 		if !strings.EqualFold(ev.Type, "Warning") {
@@ -63,7 +84,14 @@ func (c *FluxEventCollector) CollectFailedKustomizations(ctx context.Context) ([
 		if !strings.EqualFold(ev.InvolvedKind, string(types.FluxResourceKindKustomization)) {
 			continue
 		}
-		if !isReconciliationFailure(ev.Reason, ev.Message) {
+		if !ruleSet.Match(rules.Signal{
+			Kind:      types.FluxResourceKindKustomization,
+			Namespace: ev.Namespace,
+			Name:      ev.Name,
+			Reason:    ev.Reason,
+			Message:   ev.Message,
+			Origin:    rules.OriginEvent,
+		}) {
 			continue
 		}
 
@@ -100,43 +128,13 @@ func (c *FluxEventCollector) CollectFailedKustomizations(ctx context.Context) ([
 		sort.Strings(ctxEvents.Events)
 		out = append(out, ctxEvents)
 	}
-	return out, nil
-}
-
-func isReconciliationFailure(reason, message string) bool {
-	reasonLower := strings.ToLower(reason)
-	msgLower := strings.ToLower(message)
-	if strings.Contains(reasonLower, "reconciliationfailed") || strings.Contains(reasonLower, "ready") {
-		return true
-	}
-	failureHints := []string{"reconciliation failed", "apply failed", "health check failed", "dependency not ready"}
-	for _, hint := range failureHints {
-		if strings.Contains(msgLower, hint) {
-			return true
-		}
-	}
-	return false
+	return out
 }
 
 func formatEvent(ev K8sEvent) string {
-	return fmt.Sprintf("[%s] %s: %s", ev.Timestamp.UTC().Format(time.RFC3339), ev.Reason, ev.Message)
-}
-
-// KubernetesEventLister is a placeholder for a real implementation using client-go.
-// KubernetesEventLister nutzt client-go, um Events aus einem echten Cluster zu listen.
-type KubernetesEventLister struct {
-	Client Interface // Interface ist ein Platzhalter für client-go Event-Client
-}
-
-// Interface ist ein Platzhalter für das client-go Event-API-Interface
-type Interface interface {
-	List(ctx context.Context, namespace string) ([]K8sEvent, error)
-}
-
-// ListEvents ruft Events aus dem Cluster ab (client-go Integration erforderlich)
-func (k KubernetesEventLister) ListEvents(ctx context.Context, namespace string) ([]K8sEvent, error) {
-	if k.Client == nil {
-		return nil, errors.New("client-go Event-Client nicht konfiguriert")
+	line := fmt.Sprintf("[%s] %s: %s", ev.Timestamp.UTC().Format(time.RFC3339), ev.Reason, ev.Message)
+	if ev.Count > 1 {
+		line += fmt.Sprintf(" (x%d)", ev.Count)
 	}
-	return k.Client.List(ctx, namespace)
+	return line
 }
@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/afeldman/fluxbrain/internal/rules"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// ErrorSink receives ErrorContexts as soon as a collector observes them.
+type ErrorSink func(ctx context.Context, ec types.ErrorContext)
+
+const involvedObjectIndex = "involvedObject"
+
+// DefaultWatchDebounce coalesces bursts of events for the same resource.
+const DefaultWatchDebounce = 2 * time.Second
+
+// FluxEventWatcher keeps a shared informer on Warning events and pushes
+// ErrorContexts for affected resources to a sink as events arrive.
+// The ErrorContext shape is identical to FluxEventCollector's: every push
+// contains all cached failure events of the involved resource.
+type FluxEventWatcher struct {
+	Client   kubernetes.Interface
+	Resync   time.Duration
+	Debounce time.Duration
+	// Rules classifies events as failures; nil uses rules.Default().
+	Rules *rules.Set
+	// Logs is optional; without it no controller logs are collected.
+	Logs *LogCollector
+
+	collector *FluxEventCollector
+
+	mu      sync.Mutex
+	queue   workqueue.DelayingInterface
+	indexer cache.Indexer
+}
+
+// NewFluxEventWatcher constructs a watcher for a specific cluster/namespace.
+func NewFluxEventWatcher(cluster, namespace string, client kubernetes.Interface) *FluxEventWatcher {
+	return &FluxEventWatcher{
+		Client:    client,
+		Debounce:  DefaultWatchDebounce,
+		collector: NewFluxEventCollector(cluster, namespace, nil),
+	}
+}
+
+// Run starts the informer and blocks until ctx is canceled.
+// Bookmarks and relists after "too old resource version" are handled by the
+// informer's reflector; the watcher only ignores the resulting no-op updates.
+func (w *FluxEventWatcher) Run(ctx context.Context, sink ErrorSink) error {
+	if w.Client == nil {
+		return errors.New("kubernetes client is not configured")
+	}
+	w.collector.Rules = w.Rules
+
+	factory := informers.NewSharedInformerFactoryWithOptions(w.Client, w.Resync,
+		informers.WithNamespace(w.collector.Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = "type=" + corev1.EventTypeWarning
+		}),
+	)
+	informer := factory.Core().V1().Events().Informer()
+	if err := informer.AddIndexers(cache.Indexers{involvedObjectIndex: indexInvolvedObject}); err != nil {
+		return fmt.Errorf("add event indexer: %w", err)
+	}
+	if err := informer.SetWatchErrorHandler(watchErrorHandler); err != nil {
+		return fmt.Errorf("set watch error handler: %w", err)
+	}
+
+	queue := workqueue.NewDelayingQueueWithConfig(workqueue.DelayingQueueConfig{Name: "fluxbrain-events"})
+	defer queue.ShutDown()
+
+	enqueue := func(obj interface{}) {
+		ev, ok := obj.(*corev1.Event)
+		if !ok {
+			return
+		}
+		queue.AddAfter(involvedObjectKey(ev), w.Debounce)
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldEv, _ := oldObj.(*corev1.Event)
+			newEv, _ := newObj.(*corev1.Event)
+			if oldEv != nil && newEv != nil && oldEv.ResourceVersion == newEv.ResourceVersion {
+				return // resync or relist, nothing new
+			}
+			enqueue(newObj)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return ctx.Err()
+	}
+	slog.Info("event watcher synced", "namespace", w.collector.Namespace)
+
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+
+	indexer := informer.GetIndexer()
+	w.mu.Lock()
+	w.queue, w.indexer = queue, indexer
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.queue, w.indexer = nil, nil
+		w.mu.Unlock()
+	}()
+
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return ctx.Err()
+		}
+		w.process(ctx, indexer, item.(string), sink)
+		queue.Done(item)
+	}
+}
+
+// Replay pushes every cached resource to the sink again, e.g. after this
+// replica became leader while its sink was gated. It is a no-op before the
+// informer synced.
+func (w *FluxEventWatcher) Replay() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.queue == nil {
+		return
+	}
+	for _, key := range w.indexer.ListIndexFuncValues(involvedObjectIndex) {
+		w.queue.Add(key)
+	}
+}
+
+func (w *FluxEventWatcher) process(ctx context.Context, indexer cache.Indexer, key string, sink ErrorSink) {
+	objs, err := indexer.ByIndex(involvedObjectIndex, key)
+	if err != nil {
+		slog.Warn("event watcher index lookup failed", "object", key, "error", err)
+		return
+	}
+
+	events := make([]K8sEvent, 0, len(objs))
+	for _, obj := range objs {
+		if ev, ok := obj.(*corev1.Event); ok {
+			events = append(events, FromCoreEvent(ev))
+		}
+	}
+	for _, ec := range w.collector.contextsFromEvents(events) {
+		attachLogs(ctx, w.Logs, &ec)
+		sink(ctx, ec)
+	}
+}
+
+func indexInvolvedObject(obj interface{}) ([]string, error) {
+	ev, ok := obj.(*corev1.Event)
+	if !ok {
+		return nil, nil
+	}
+	return []string{involvedObjectKey(ev)}, nil
+}
+
+func involvedObjectKey(ev *corev1.Event) string {
+	ref := ev.InvolvedObject
+	return ref.Kind + "/" + ref.Namespace + "/" + ref.Name
+}
+
+func watchErrorHandler(r *cache.Reflector, err error) {
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		slog.Info("event watch expired, relisting", "error", err)
+		return
+	}
+	cache.DefaultWatchErrorHandler(r, err)
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestFluxEventWatcherPushesFailures(t *testing.T) {
+	base := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset(&corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "apps.1", Namespace: "flux-system"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "ReconciliationFailed",
+		Message:        "apply failed",
+		LastTimestamp:  metav1.NewTime(base),
+	})
+
+	w := NewFluxEventWatcher("prod", "flux-system", client)
+	w.Debounce = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got := make(chan types.ErrorContext, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(_ context.Context, ec types.ErrorContext) { got <- ec })
+	}()
+
+	first := receive(t, got)
+	if first.Resource.Name != "apps" || first.Cluster != "prod" || len(first.Events) != 1 {
+		t.Fatalf("unexpected initial context: %+v", first)
+	}
+
+	_, err := client.CoreV1().Events("flux-system").Create(ctx, &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "apps.2", Namespace: "flux-system"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "HealthCheckFailed",
+		Message:        "health check failed after 30s",
+		LastTimestamp:  metav1.NewTime(base.Add(time.Minute)),
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+
+	second := receive(t, got)
+	if len(second.Events) != 2 || second.Reason != "ReconciliationFailed" {
+		t.Fatalf("pushed context should aggregate all events of the resource: %+v", second)
+	}
+
+	w.Replay()
+	if replayed := receive(t, got); replayed.Resource.Name != "apps" || len(replayed.Events) != 2 {
+		t.Fatalf("replay should push cached resources again: %+v", replayed)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func receive(t *testing.T, ch <-chan types.ErrorContext) types.ErrorContext {
+	t.Helper()
+	select {
+	case ec := <-ch:
+		return ec
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for pushed ErrorContext")
+		return types.ErrorContext{}
+	}
+}
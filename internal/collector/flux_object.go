@@ -0,0 +1,177 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/afeldman/fluxbrain/internal/rules"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// conditionReady is the summary condition every Flux object reports.
+const conditionReady = "Ready"
+
+// listFluxObjects lists all objects of a Flux kind in the namespace ("" lists all namespaces).
+// A missing CRD is not an error: the kind is simply not installed.
+func listFluxObjects(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	list, err := client.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list %s: %w", gvr.Resource, err)
+	}
+	return list.Items, nil
+}
+
+// sortContexts orders contexts by namespace and name for deterministic output.
+func sortContexts(out []types.ErrorContext) {
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Resource, out[j].Resource
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+}
+
+// ruleSetOr returns s, or the default rule set when s is nil.
+func ruleSetOr(s *rules.Set) *rules.Set {
+	if s == nil {
+		return rules.Default()
+	}
+	return s
+}
+
+// conditionSignal describes a failing condition of obj for rule evaluation.
+func conditionSignal(kind types.FluxResourceKind, obj *unstructured.Unstructured, cond types.Condition) rules.Signal {
+	return rules.Signal{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Reason:    cond.Reason,
+		Message:   cond.Message,
+		Labels:    obj.GetLabels(),
+		Origin:    rules.OriginCondition,
+	}
+}
+
+// conditionsOf reads status.conditions from a Flux object.
+// If only is non-empty, conditions of other types are skipped.
+func conditionsOf(obj *unstructured.Unstructured, only ...string) []types.Condition {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	out := make([]types.Condition, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cond := types.Condition{
+			Type:    stringField(m, "type"),
+			Status:  stringField(m, "status"),
+			Reason:  stringField(m, "reason"),
+			Message: stringField(m, "message"),
+		}
+		if ts, err := parseTime(stringField(m, "lastTransitionTime")); err == nil {
+			cond.LastTransitionTime = ts
+		}
+		if len(only) > 0 && !contains(only, cond.Type) {
+			continue
+		}
+		out = append(out, cond)
+	}
+	return out
+}
+
+// findCondition returns the condition of the given type.
+func findCondition(conds []types.Condition, typ string) (types.Condition, bool) {
+	for _, c := range conds {
+		if c.Type == typ {
+			return c, true
+		}
+	}
+	return types.Condition{}, false
+}
+
+// isNotReady reports whether the object has a Ready condition with status False.
+// Objects without a Ready condition or with Ready=Unknown are still progressing.
+func isNotReady(conds []types.Condition) bool {
+	ready, ok := findCondition(conds, conditionReady)
+	return ok && ready.Status == "False"
+}
+
+// sourceRefOf formats spec.sourceRef (or the given path) as Kind/namespace/name.
+func sourceRefOf(obj *unstructured.Unstructured, path ...string) string {
+	if len(path) == 0 {
+		path = []string{"spec", "sourceRef"}
+	}
+	ref, ok, _ := unstructured.NestedStringMap(obj.Object, path...)
+	if !ok || ref["name"] == "" {
+		return ""
+	}
+	ns := ref["namespace"]
+	if ns == "" {
+		ns = obj.GetNamespace()
+	}
+	return fmt.Sprintf("%s/%s/%s", ref["kind"], ns, ref["name"])
+}
+
+// eventsFor formats all Warning events that belong to the given object.
+func eventsFor(events []K8sEvent, kind types.FluxResourceKind, namespace, name string) []string {
+	var out []string
+	for _, ev := range events {
+		if !strings.EqualFold(ev.Type, "Warning") {
+			continue
+		}
+		if !strings.EqualFold(ev.InvolvedKind, string(kind)) || ev.Namespace != namespace || ev.Name != name {
+			continue
+		}
+		out = append(out, formatEvent(ev))
+	}
+	return out
+}
+
+// parseTime parses a Kubernetes RFC3339 timestamp as UTC.
+func parseTime(v string) (time.Time, error) {
+	ts, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.UTC(), nil
+}
+
+func nestedString(obj *unstructured.Unstructured, fields ...string) string {
+	v, _, _ := unstructured.NestedString(obj.Object, fields...)
+	return v
+}
+
+func nestedInt(obj *unstructured.Unstructured, fields ...string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+	return v
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
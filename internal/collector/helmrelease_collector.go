@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/afeldman/fluxbrain/internal/rules"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// HelmReleaseGVR addresses helm-controller's HelmRelease API.
+var HelmReleaseGVR = schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"}
+
+// helmReleaseConditions are the HelmRelease conditions copied into the context.
+var helmReleaseConditions = []string{"Ready", "Released", "TestSuccess", "Remediated"}
+
+// HelmReleaseCollector emits ErrorContext objects for HelmReleases with Ready=False,
+// enriched with their Warning events and chart facts.
+type HelmReleaseCollector struct {
+	Cluster   string
+	Namespace string
+	Client    dynamic.Interface
+	// Lister is optional; without it contexts carry no events.
+	Lister EventLister
+	// Rules classifies failing conditions; nil uses rules.Default().
+	Rules *rules.Set
+	// Logs is optional; without it no controller logs are collected.
+	Logs *LogCollector
+}
+
+// NewHelmReleaseCollector constructs a collector for a specific cluster/namespace.
+func NewHelmReleaseCollector(cluster, namespace string, client dynamic.Interface, lister EventLister) *HelmReleaseCollector {
+	return &HelmReleaseCollector{
+		Cluster:   cluster,
+		Namespace: namespace,
+		Client:    client,
+		Lister:    lister,
+	}
+}
+
+// CollectFailedHelmReleases lists HelmReleases and returns contexts for those not Ready.
+func (c *HelmReleaseCollector) CollectFailedHelmReleases(ctx context.Context) ([]types.ErrorContext, error) {
+	if c.Client == nil {
+		return nil, errors.New("dynamic client is not configured")
+	}
+
+	items, err := listFluxObjects(ctx, c.Client, HelmReleaseGVR, c.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []K8sEvent
+	if c.Lister != nil && len(items) > 0 {
+		events, err = c.Lister.ListEvents(ctx, c.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ruleSet := ruleSetOr(c.Rules)
+	out := make([]types.ErrorContext, 0)
+	for i := range items {
+		hr := &items[i]
+		conds := conditionsOf(hr, helmReleaseConditions...)
+		if !isNotReady(conds) {
+			continue
+		}
+		ready, _ := findCondition(conds, conditionReady)
+		if !ruleSet.Match(conditionSignal(types.FluxResourceKindHelmRelease, hr, ready)) {
+			continue
+		}
+		ec := c.contextFor(hr, conds, events)
+		attachLogs(ctx, c.Logs, &ec)
+		out = append(out, ec)
+	}
+
+	sortContexts(out)
+	return out, nil
+}
+
+func (c *HelmReleaseCollector) contextFor(hr *unstructured.Unstructured, conds []types.Condition, events []K8sEvent) types.ErrorContext {
+	ready, _ := findCondition(conds, conditionReady)
+	chart := chartOf(hr)
+
+	evs := eventsFor(events, types.FluxResourceKindHelmRelease, hr.GetNamespace(), hr.GetName())
+	sort.Strings(evs)
+
+	return types.ErrorContext{
+		Source:  "flux-status",
+		Cluster: c.Cluster,
+		Resource: types.ResourceRef{
+			Kind:      types.FluxResourceKindHelmRelease,
+			Name:      hr.GetName(),
+			Namespace: hr.GetNamespace(),
+		},
+		Git: types.GitContext{
+			Repository: helmSourceRef(hr),
+			Revision:   chart.LastAttemptedRevision,
+		},
+		Chart:      &chart,
+		Conditions: conds,
+		ErrorMsg:   ready.Message,
+		Reason:     ready.Reason,
+		Events:     evs,
+		Timestamp:  ready.LastTransitionTime,
+	}
+}
+
+// chartOf reads chart name/version from the latest release snapshot (helm.toolkit.fluxcd.io/v2)
+// and falls back to the chart template in the spec.
+func chartOf(hr *unstructured.Unstructured) types.ChartContext {
+	chart := types.ChartContext{
+		LastAttemptedRevision: nestedString(hr, "status", "lastAttemptedRevision"),
+		InstallFailures:       nestedInt(hr, "status", "installFailures"),
+		UpgradeFailures:       nestedInt(hr, "status", "upgradeFailures"),
+	}
+
+	if history, ok, _ := unstructured.NestedSlice(hr.Object, "status", "history"); ok && len(history) > 0 {
+		if latest, ok := history[0].(map[string]interface{}); ok {
+			chart.Name = stringField(latest, "chartName")
+			chart.Version = stringField(latest, "chartVersion")
+		}
+	}
+	if chart.Name == "" {
+		chart.Name = nestedString(hr, "spec", "chart", "spec", "chart")
+	}
+	if chart.Version == "" {
+		chart.Version = chart.LastAttemptedRevision
+	}
+	if chart.Version == "" {
+		chart.Version = nestedString(hr, "spec", "chart", "spec", "version")
+	}
+	return chart
+}
+
+// helmSourceRef returns the chart source of a HelmRelease (chart template or chartRef).
+func helmSourceRef(hr *unstructured.Unstructured) string {
+	if ref := sourceRefOf(hr, "spec", "chart", "spec", "sourceRef"); ref != "" {
+		return ref
+	}
+	return sourceRefOf(hr, "spec", "chartRef")
+}
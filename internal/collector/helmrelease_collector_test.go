@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+type staticLister []K8sEvent
+
+func (s staticLister) ListEvents(context.Context, string) ([]K8sEvent, error) {
+	return s, nil
+}
+
+func helmRelease(name, readyStatus string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "helm.toolkit.fluxcd.io/v2",
+		"kind":       "HelmRelease",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "apps"},
+		"spec": map[string]interface{}{
+			"chart": map[string]interface{}{"spec": map[string]interface{}{
+				"chart":     "podinfo",
+				"version":   "6.x",
+				"sourceRef": map[string]interface{}{"kind": "HelmRepository", "name": "podinfo", "namespace": "flux-system"},
+			}},
+		},
+		"status": map[string]interface{}{
+			"lastAttemptedRevision": "6.5.4",
+			"installFailures":       int64(0),
+			"upgradeFailures":       int64(3),
+			"history": []interface{}{
+				map[string]interface{}{"chartName": "podinfo", "chartVersion": "6.5.4"},
+			},
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"type": "Ready", "status": readyStatus, "reason": "UpgradeFailed",
+					"message":            "Helm upgrade failed: timed out waiting for the condition",
+					"lastTransitionTime": "2024-12-24T10:00:00Z",
+				},
+				map[string]interface{}{"type": "Released", "status": "False", "reason": "UpgradeFailed"},
+				map[string]interface{}{"type": "Reconciling", "status": "True"},
+			},
+		},
+	}}
+}
+
+func TestHelmReleaseCollector(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{HelmReleaseGVR: "HelmReleaseList"},
+		helmRelease("podinfo", "False"),
+		helmRelease("healthy", "True"),
+	)
+	events := staticLister{
+		{InvolvedKind: "HelmRelease", Name: "podinfo", Namespace: "apps", Type: "Warning", Reason: "UpgradeFailed", Message: "timed out", Timestamp: time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)},
+		{InvolvedKind: "HelmRelease", Name: "podinfo", Namespace: "apps", Type: "Normal", Reason: "Progressing"},
+		{InvolvedKind: "Kustomization", Name: "podinfo", Namespace: "apps", Type: "Warning", Reason: "ReconciliationFailed"},
+	}
+
+	c := NewHelmReleaseCollector("prod", "apps", client, events)
+	got, err := c.CollectErrors(context.Background())
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one failed HelmRelease, got %d", len(got))
+	}
+
+	ec := got[0]
+	if ec.Resource.Kind != types.FluxResourceKindHelmRelease || ec.Resource.Name != "podinfo" || ec.Reason != "UpgradeFailed" {
+		t.Errorf("unexpected resource/reason: %+v", ec)
+	}
+	if ec.Git.Repository != "HelmRepository/flux-system/podinfo" || ec.Git.Revision != "6.5.4" {
+		t.Errorf("unexpected git context: %+v", ec.Git)
+	}
+	want := types.ChartContext{Name: "podinfo", Version: "6.5.4", LastAttemptedRevision: "6.5.4", UpgradeFailures: 3}
+	if ec.Chart == nil || *ec.Chart != want {
+		t.Errorf("unexpected chart context: %+v", ec.Chart)
+	}
+	if len(ec.Conditions) != 2 {
+		t.Errorf("expected Ready and Released conditions, got %+v", ec.Conditions)
+	}
+	if len(ec.Events) != 1 {
+		t.Errorf("expected only the HelmRelease warning event, got %v", ec.Events)
+	}
+	if !ec.Timestamp.Equal(time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("timestamp should be the Ready transition time, got %s", ec.Timestamp)
+	}
+}
+
+func TestHelmReleaseCollectorAttachesControllerLogs(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{HelmReleaseGVR: "HelmReleaseList"},
+		helmRelease("podinfo", "False"),
+	)
+	pods := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "helm-controller-xyz", Namespace: "flux-system", Labels: map[string]string{"app": "helm-controller"}}},
+	)
+	line := `{"level":"error","ts":"2024-12-24T10:00:05.000Z","msg":"upgrade failed","controllerKind":"HelmRelease","name":"podinfo","namespace":"apps"}`
+	logs := NewLogCollector(pods, "flux-system")
+	logs.Streamer = &fakeStreamer{logs: map[string]string{"helm-controller-xyz": line}}
+
+	c := NewHelmReleaseCollector("prod", "apps", client, nil)
+	c.Logs = logs
+	got, err := c.CollectErrors(context.Background())
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	if len(got) != 1 || len(got[0].LogSnippets) != 1 {
+		t.Fatalf("expected one log snippet, got %+v", got)
+	}
+	if sn := got[0].LogSnippets[0]; !strings.HasPrefix(sn, "helm-controller/helm-controller-xyz@") || !strings.Contains(sn, "upgrade failed") {
+		t.Errorf("unexpected snippet %q", sn)
+	}
+}
+
+func TestHelmReleaseCollectorSkipsEventsWithoutHelmReleases(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{HelmReleaseGVR: "HelmReleaseList"},
+	)
+	lister := &countingLister{}
+	if _, err := NewHelmReleaseCollector("prod", "apps", client, lister).CollectErrors(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if lister.calls != 0 {
+		t.Errorf("events listed %d times without HelmReleases", lister.calls)
+	}
+}
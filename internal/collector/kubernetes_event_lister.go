@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/pager"
+)
+
+// EventAPI selects the Kubernetes Event API read by KubernetesEventLister.
+type EventAPI string
+
+const (
+	// EventAPICoreV1 reads core/v1 Events (involvedObject, lastTimestamp, count).
+	EventAPICoreV1 EventAPI = "core/v1"
+	// EventAPIEventsV1 reads events.k8s.io/v1 Events (regarding, series).
+	EventAPIEventsV1 EventAPI = "events.k8s.io/v1"
+)
+
+// DefaultEventPageSize limits how many events are fetched per list request.
+const DefaultEventPageSize int64 = 500
+
+// KubernetesEventLister lists Events from a live cluster via client-go.
+type KubernetesEventLister struct {
+	Client   kubernetes.Interface
+	API      EventAPI
+	PageSize int64
+}
+
+// NewKubernetesEventLister returns a lister for the given Event API.
+// An empty api defaults to core/v1.
+func NewKubernetesEventLister(client kubernetes.Interface, api EventAPI) KubernetesEventLister {
+	if api == "" {
+		api = EventAPICoreV1
+	}
+	return KubernetesEventLister{
+		Client:   client,
+		API:      api,
+		PageSize: DefaultEventPageSize,
+	}
+}
+
+// ListEvents pages through all Events in the namespace ("" lists all namespaces).
+func (k KubernetesEventLister) ListEvents(ctx context.Context, namespace string) ([]K8sEvent, error) {
+	if k.Client == nil {
+		return nil, errors.New("kubernetes client is not configured")
+	}
+
+	var listFn func(opts metav1.ListOptions) (runtime.Object, error)
+	switch k.API {
+	case "", EventAPICoreV1:
+		listFn = func(opts metav1.ListOptions) (runtime.Object, error) {
+			return k.Client.CoreV1().Events(namespace).List(ctx, opts)
+		}
+	case EventAPIEventsV1:
+		listFn = func(opts metav1.ListOptions) (runtime.Object, error) {
+			return k.Client.EventsV1().Events(namespace).List(ctx, opts)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event api %q", k.API)
+	}
+
+	p := pager.New(pager.SimplePageFunc(listFn))
+	if k.PageSize > 0 {
+		p.PageSize = k.PageSize
+	}
+
+	var out []K8sEvent
+	err := p.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		switch ev := obj.(type) {
+		case *corev1.Event:
+			out = append(out, FromCoreEvent(ev))
+		case *eventsv1.Event:
+			out = append(out, FromEventsV1Event(ev))
+		default:
+			return fmt.Errorf("unexpected event object %T", obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list events in %q: %w", namespace, err)
+	}
+	return out, nil
+}
+
+// FromCoreEvent maps a core/v1 Event into a K8sEvent.
+func FromCoreEvent(ev *corev1.Event) K8sEvent {
+	count := ev.Count
+	ts := ev.LastTimestamp.Time
+	if ev.Series != nil {
+		count = ev.Series.Count
+		if ts.IsZero() {
+			ts = ev.Series.LastObservedTime.Time
+		}
+	}
+	if ts.IsZero() {
+		ts = ev.EventTime.Time
+	}
+	if ts.IsZero() {
+		ts = ev.FirstTimestamp.Time
+	}
+	if ts.IsZero() {
+		ts = ev.CreationTimestamp.Time
+	}
+
+	source := ev.Source.Component
+	if source == "" {
+		source = ev.ReportingController
+	}
+
+	return K8sEvent{
+		InvolvedKind: ev.InvolvedObject.Kind,
+		Name:         ev.InvolvedObject.Name,
+		Namespace:    ev.InvolvedObject.Namespace,
+		Reason:       ev.Reason,
+		Message:      ev.Message,
+		Type:         ev.Type,
+		Count:        count,
+		Timestamp:    ts,
+		Source:       source,
+	}
+}
+
+// FromEventsV1Event maps an events.k8s.io/v1 Event into a K8sEvent.
+func FromEventsV1Event(ev *eventsv1.Event) K8sEvent {
+	count := ev.DeprecatedCount
+	var ts metav1.Time
+	if ev.Series != nil {
+		count = ev.Series.Count
+		ts = metav1.Time{Time: ev.Series.LastObservedTime.Time}
+	}
+	if ts.IsZero() {
+		ts = ev.DeprecatedLastTimestamp
+	}
+	if ts.IsZero() {
+		ts = metav1.Time{Time: ev.EventTime.Time}
+	}
+	if ts.IsZero() {
+		ts = ev.DeprecatedFirstTimestamp
+	}
+	if ts.IsZero() {
+		ts = ev.CreationTimestamp
+	}
+
+	source := ev.ReportingController
+	if source == "" {
+		source = ev.DeprecatedSource.Component
+	}
+
+	return K8sEvent{
+		InvolvedKind: ev.Regarding.Kind,
+		Name:         ev.Regarding.Name,
+		Namespace:    ev.Regarding.Namespace,
+		Reason:       ev.Reason,
+		Message:      ev.Note,
+		Type:         ev.Type,
+		Count:        count,
+		Timestamp:    ts.Time,
+		Source:       source,
+	}
+}
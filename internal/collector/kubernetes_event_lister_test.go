@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesEventListerCoreV1(t *testing.T) {
+	base := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset(
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "a.1", Namespace: "flux-system"},
+			InvolvedObject: corev1.ObjectReference{
+				Kind:      "Kustomization",
+				Name:      "apps",
+				Namespace: "flux-system",
+			},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "ReconciliationFailed",
+			Message:        "apply failed",
+			Count:          3,
+			FirstTimestamp: metav1.NewTime(base),
+			LastTimestamp:  metav1.NewTime(base.Add(time.Minute)),
+			Source:         corev1.EventSource{Component: "kustomize-controller"},
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "b.1", Namespace: "flux-system"},
+			InvolvedObject: corev1.ObjectReference{
+				Kind:      "HelmRelease",
+				Name:      "podinfo",
+				Namespace: "flux-system",
+			},
+			Type:                corev1.EventTypeNormal,
+			Reason:              "InstallSucceeded",
+			EventTime:           metav1.NewMicroTime(base),
+			Series:              &corev1.EventSeries{Count: 7, LastObservedTime: metav1.NewMicroTime(base.Add(2 * time.Minute))},
+			ReportingController: "helm-controller",
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "c.1", Namespace: "other"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "ignored", Namespace: "other"},
+		},
+	)
+
+	lister := NewKubernetesEventLister(client, EventAPICoreV1)
+	events, err := lister.ListEvents(context.Background(), "flux-system")
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d: %+v", len(events), events)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+
+	want := K8sEvent{
+		InvolvedKind: "Kustomization",
+		Name:         "apps",
+		Namespace:    "flux-system",
+		Reason:       "ReconciliationFailed",
+		Message:      "apply failed",
+		Type:         "Warning",
+		Count:        3,
+		Timestamp:    base.Add(time.Minute),
+		Source:       "kustomize-controller",
+	}
+	if !equalEvent(events[0], want) {
+		t.Errorf("unexpected core event mapping:\nwant %+v\ngot  %+v", want, events[0])
+	}
+
+	series := events[1]
+	if series.Count != 7 || !series.Timestamp.Equal(base.Add(2*time.Minute)) || series.Source != "helm-controller" {
+		t.Errorf("series not mapped: %+v", series)
+	}
+}
+
+func TestKubernetesEventListerEventsV1(t *testing.T) {
+	base := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset(
+		&eventsv1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "a.1", Namespace: "flux-system"},
+			Regarding: corev1.ObjectReference{
+				Kind:      "Kustomization",
+				Name:      "apps",
+				Namespace: "flux-system",
+			},
+			Type:                "Warning",
+			Reason:              "HealthCheckFailed",
+			Note:                "health check failed after 30s",
+			EventTime:           metav1.NewMicroTime(base),
+			Series:              &eventsv1.EventSeries{Count: 4, LastObservedTime: metav1.NewMicroTime(base.Add(time.Minute))},
+			ReportingController: "kustomize-controller",
+		},
+	)
+
+	lister := NewKubernetesEventLister(client, EventAPIEventsV1)
+	events, err := lister.ListEvents(context.Background(), "flux-system")
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+
+	want := K8sEvent{
+		InvolvedKind: "Kustomization",
+		Name:         "apps",
+		Namespace:    "flux-system",
+		Reason:       "HealthCheckFailed",
+		Message:      "health check failed after 30s",
+		Type:         "Warning",
+		Count:        4,
+		Timestamp:    base.Add(time.Minute),
+		Source:       "kustomize-controller",
+	}
+	if len(events) != 1 || !equalEvent(events[0], want) {
+		t.Fatalf("unexpected events.k8s.io mapping:\nwant %+v\ngot  %+v", want, events)
+	}
+}
+
+func TestKubernetesEventListerFeedsCollector(t *testing.T) {
+	base := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset(&corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "a.1", Namespace: "flux-system"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Kustomization", Name: "apps", Namespace: "flux-system"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "ReconciliationFailed",
+		Message:        "apply failed",
+		LastTimestamp:  metav1.NewTime(base),
+	})
+
+	c := NewFluxEventCollector("prod", "flux-system", NewKubernetesEventLister(client, ""))
+	got, err := c.CollectFailedKustomizations(context.Background())
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Resource.Name != "apps" || got[0].Cluster != "prod" {
+		t.Fatalf("unexpected contexts: %+v", got)
+	}
+}
+
+func equalEvent(a, b K8sEvent) bool {
+	ts := a.Timestamp.Equal(b.Timestamp)
+	a.Timestamp, b.Timestamp = time.Time{}, time.Time{}
+	return ts && a == b
+}
@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	fluxcontext "github.com/afeldman/fluxbrain/internal/context"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Defaults for LogCollector.
+const (
+	DefaultLogWindow   = 5 * time.Minute
+	DefaultLogMaxLines = 50
+)
+
+// LogStreamer opens a container log stream starting at since.
+type LogStreamer interface {
+	StreamLogs(ctx context.Context, namespace, pod, container string, since time.Time) (io.ReadCloser, error)
+}
+
+// PodLogStreamer streams logs through the Kubernetes pod log API.
+type PodLogStreamer struct {
+	Client kubernetes.Interface
+}
+
+// StreamLogs implements LogStreamer.
+func (p PodLogStreamer) StreamLogs(ctx context.Context, namespace, pod, container string, since time.Time) (io.ReadCloser, error) {
+	sinceTime := metav1.NewTime(since)
+	return p.Client.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		SinceTime: &sinceTime,
+	}).Stream(ctx)
+}
+
+// controllerFor maps a Flux kind to the controller that reconciles it.
+func controllerFor(kind types.FluxResourceKind) string {
+	switch kind {
+	case types.FluxResourceKindKustomization:
+		return "kustomize-controller"
+	case types.FluxResourceKindHelmRelease:
+		return "helm-controller"
+	default:
+		return "source-controller"
+	}
+}
+
+// LogCollector reads Flux controller logs around a failure and keeps only the
+// lines that belong to the failing object.
+type LogCollector struct {
+	Client    kubernetes.Interface
+	Streamer  LogStreamer
+	Namespace string
+	// Window is applied before and after the failure time.
+	Window   time.Duration
+	MaxLines int
+	// Now is used when the failure time is unknown.
+	Now func() time.Time
+}
+
+// NewLogCollector returns a collector for controllers running in namespace.
+func NewLogCollector(client kubernetes.Interface, namespace string) *LogCollector {
+	return &LogCollector{
+		Client:    client,
+		Streamer:  PodLogStreamer{Client: client},
+		Namespace: namespace,
+		Window:    DefaultLogWindow,
+		MaxLines:  DefaultLogMaxLines,
+		Now:       time.Now,
+	}
+}
+
+// fluxLogLine holds the fields of Flux's JSON log format used for matching.
+type fluxLogLine struct {
+	Timestamp      string `json:"ts"`
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	Controller     string `json:"controller"`
+	ControllerKind string `json:"controllerKind"`
+	ReconcileID    string `json:"reconcileID"`
+}
+
+// CollectLogs returns one snippet per controller pod that logged about the object.
+func (l *LogCollector) CollectLogs(ctx context.Context, status types.ResourceStatus) ([]types.LogSnippet, error) {
+	if l.Client == nil || l.Streamer == nil {
+		return nil, errors.New("log collector is not configured")
+	}
+
+	at := status.ObservedAt
+	if at.IsZero() {
+		at = l.Now()
+	}
+	from, to := at.Add(-l.Window), at.Add(l.Window)
+
+	controller := controllerFor(status.Kind)
+	pods, err := l.Client.CoreV1().Pods(l.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "app=" + controller})
+	if err != nil {
+		return nil, fmt.Errorf("list %s pods: %w", controller, err)
+	}
+
+	var out []types.LogSnippet
+	for _, pod := range pods.Items {
+		snippet, err := l.readPod(ctx, pod.Name, controller, status, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(snippet.Lines) > 0 {
+			out = append(out, snippet)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out, nil
+}
+
+// attachLogs sets ec.LogSnippets to the controller logs around ec's failure.
+// It is a no-op without logs; a failing log API is logged, since logs are
+// supplementary and must not block the context.
+func attachLogs(ctx context.Context, logs *LogCollector, ec *types.ErrorContext) {
+	if logs == nil {
+		return
+	}
+	snippets, err := logs.CollectLogs(ctx, types.ResourceStatus{
+		Kind:       ec.Resource.Kind,
+		Name:       ec.Resource.Name,
+		Namespace:  ec.Resource.Namespace,
+		ObservedAt: ec.Timestamp,
+	})
+	if err != nil {
+		slog.Warn("controller log collection failed", "kind", string(ec.Resource.Kind), "namespace", ec.Resource.Namespace, "name", ec.Resource.Name, "error", err)
+	}
+	ec.LogSnippets = fluxcontext.FormatLogs(snippets)
+}
+
+func (l *LogCollector) readPod(ctx context.Context, pod, container string, status types.ResourceStatus, from, to time.Time) (types.LogSnippet, error) {
+	stream, err := l.Streamer.StreamLogs(ctx, l.Namespace, pod, container, from)
+	if err != nil {
+		return types.LogSnippet{}, fmt.Errorf("stream logs of %s/%s: %w", l.Namespace, pod, err)
+	}
+	defer stream.Close()
+
+	// lines of the object's reconciliations may omit the object fields and
+	// only carry the reconcileID, which is known once all lines are read
+	type candidate struct {
+		line fluxLogLine
+		raw  string
+		ts   time.Time
+	}
+	var candidates []candidate
+	ids := make(map[string]bool)
+	if status.ReconciliationID != "" {
+		ids[status.ReconciliationID] = true
+	}
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		raw := scanner.Bytes()
+		var line fluxLogLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			continue // not a structured controller line
+		}
+		ts, err := time.Parse(time.RFC3339Nano, line.Timestamp)
+		if err != nil || ts.Before(from) {
+			continue
+		}
+		if ts.After(to) {
+			break
+		}
+		if matchesObject(line, status) {
+			if line.ReconcileID != "" {
+				ids[line.ReconcileID] = true
+			}
+		} else if line.Name != "" || line.ReconcileID == "" {
+			continue
+		}
+		candidates = append(candidates, candidate{line: line, raw: string(raw), ts: ts.UTC()})
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return types.LogSnippet{}, fmt.Errorf("read logs of %s/%s: %w", l.Namespace, pod, err)
+	}
+
+	snippet := types.LogSnippet{Source: container + "/" + pod}
+	var times []time.Time
+	for _, c := range candidates {
+		if c.line.Name == "" && !ids[c.line.ReconcileID] {
+			continue
+		}
+		times = append(times, c.ts)
+		snippet.Lines = append(snippet.Lines, c.raw)
+	}
+
+	if l.MaxLines > 0 && len(snippet.Lines) > l.MaxLines {
+		drop := len(snippet.Lines) - l.MaxLines
+		snippet.Lines, times = snippet.Lines[drop:], times[drop:]
+	}
+	if len(times) > 0 {
+		snippet.FromTime = times[0]
+	}
+	return snippet, nil
+}
+
+// matchesObject reports whether line names the object of status. A
+// controller serving several kinds (source-controller) names the kind in
+// controllerKind or, lowercased, in controller; lines without either come
+// from the controller of status.Kind and match by name alone. Lines without
+// a name match through the reconcileID of a matching line (see readPod).
+func matchesObject(line fluxLogLine, status types.ResourceStatus) bool {
+	if line.Name != status.Name || line.Namespace != status.Namespace {
+		return false
+	}
+	kind := line.ControllerKind
+	if kind == "" {
+		kind = line.Controller
+	}
+	return kind == "" || strings.EqualFold(kind, string(status.Kind))
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+type fakeStreamer struct {
+	logs  map[string]string
+	since time.Time
+}
+
+func (f *fakeStreamer) StreamLogs(_ context.Context, _, pod, _ string, since time.Time) (io.ReadCloser, error) {
+	f.since = since
+	return io.NopCloser(strings.NewReader(f.logs[pod])), nil
+}
+
+func TestLogCollectorFiltersFluxJSONLines(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kustomize-controller-abc", Namespace: "flux-system", Labels: map[string]string{"app": "kustomize-controller"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "helm-controller-xyz", Namespace: "flux-system", Labels: map[string]string{"app": "helm-controller"}}},
+	)
+
+	lines := []string{
+		`{"level":"info","ts":"2024-12-24T09:50:00.000Z","msg":"too early","name":"apps","namespace":"flux-system"}`,
+		`{"level":"error","ts":"2024-12-24T09:59:30.000Z","msg":"Reconciliation failed","name":"apps","namespace":"flux-system","reconcileID":"r1"}`,
+		`not json at all`,
+		`{"level":"info","ts":"2024-12-24T10:00:10.000Z","msg":"other object","name":"infra","namespace":"flux-system"}`,
+		`{"level":"error","ts":"2024-12-24T10:00:20.000Z","msg":"matched by kind","controllerKind":"Kustomization","name":"apps","namespace":"flux-system"}`,
+		`{"level":"info","ts":"2024-12-24T10:30:00.000Z","msg":"too late","name":"apps","namespace":"flux-system"}`,
+	}
+	streamer := &fakeStreamer{logs: map[string]string{
+		"kustomize-controller-abc": strings.Join(lines, "\n"),
+		"helm-controller-xyz":      lines[1],
+	}}
+
+	failure := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+	l := NewLogCollector(client, "flux-system")
+	l.Streamer = streamer
+
+	snippets, err := l.CollectLogs(context.Background(), types.ResourceStatus{
+		Kind:       types.FluxResourceKindKustomization,
+		Name:       "apps",
+		Namespace:  "flux-system",
+		ObservedAt: failure,
+	})
+	if err != nil {
+		t.Fatalf("collect logs failed: %v", err)
+	}
+
+	if !streamer.since.Equal(failure.Add(-DefaultLogWindow)) {
+		t.Errorf("stream should start at the window begin, got %s", streamer.since)
+	}
+	if len(snippets) != 1 {
+		t.Fatalf("expected only kustomize-controller logs, got %+v", snippets)
+	}
+	sn := snippets[0]
+	if sn.Source != "kustomize-controller/kustomize-controller-abc" {
+		t.Errorf("unexpected source %q", sn.Source)
+	}
+	if len(sn.Lines) != 2 || sn.Lines[0] != lines[1] || sn.Lines[1] != lines[4] {
+		t.Errorf("unexpected lines: %q", sn.Lines)
+	}
+	if !sn.FromTime.Equal(time.Date(2024, 12, 24, 9, 59, 30, 0, time.UTC)) {
+		t.Errorf("unexpected fromTime %s", sn.FromTime)
+	}
+}
+
+func TestLogCollectorSeparatesSameNamedSourcesByKind(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "source-controller-abc", Namespace: "flux-system", Labels: map[string]string{"app": "source-controller"}}},
+	)
+	lines := []string{
+		`{"level":"error","ts":"2024-12-24T10:00:00.000Z","msg":"clone failed","controller":"gitrepository","controllerKind":"GitRepository","name":"podinfo","namespace":"flux-system"}`,
+		`{"level":"error","ts":"2024-12-24T10:00:01.000Z","msg":"index fetch failed","controller":"helmrepository","controllerKind":"HelmRepository","name":"podinfo","namespace":"flux-system"}`,
+		`{"level":"error","ts":"2024-12-24T10:00:02.000Z","msg":"controller only","controller":"helmrepository","name":"podinfo","namespace":"flux-system"}`,
+	}
+	l := NewLogCollector(client, "flux-system")
+	l.Streamer = &fakeStreamer{logs: map[string]string{"source-controller-abc": strings.Join(lines, "\n")}}
+	failure := time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)
+
+	for kind, want := range map[types.FluxResourceKind][]string{
+		types.FluxResourceKindGitRepository:  {lines[0]},
+		types.FluxResourceKindHelmRepository: {lines[1], lines[2]},
+	} {
+		snippets, err := l.CollectLogs(context.Background(), types.ResourceStatus{Kind: kind, Name: "podinfo", Namespace: "flux-system", ObservedAt: failure})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snippets) != 1 || strings.Join(snippets[0].Lines, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%s: lines = %+v, want %q", kind, snippets, want)
+		}
+	}
+}
+
+func TestLogCollectorKeepsLinesOfTheObjectsReconcileIDs(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kustomize-controller-abc", Namespace: "flux-system", Labels: map[string]string{"app": "kustomize-controller"}}},
+	)
+	lines := []string{
+		`{"level":"info","ts":"2024-12-24T09:59:00.000Z","msg":"before the named line","reconcileID":"r1"}`,
+		`{"level":"error","ts":"2024-12-24T09:59:10.000Z","msg":"Reconciliation failed","name":"apps","namespace":"flux-system","reconcileID":"r1"}`,
+		`{"level":"info","ts":"2024-12-24T09:59:20.000Z","msg":"other reconciliation","reconcileID":"r2"}`,
+		`{"level":"info","ts":"2024-12-24T09:59:30.000Z","msg":"other object","name":"infra","namespace":"flux-system","reconcileID":"r1"}`,
+		`{"level":"info","ts":"2024-12-24T09:59:40.000Z","msg":"from the status","reconcileID":"r3"}`,
+		`{"level":"info","ts":"2024-12-24T09:59:50.000Z","msg":"no id"}`,
+	}
+	l := NewLogCollector(client, "flux-system")
+	l.Streamer = &fakeStreamer{logs: map[string]string{"kustomize-controller-abc": strings.Join(lines, "\n")}}
+
+	snippets, err := l.CollectLogs(context.Background(), types.ResourceStatus{
+		Kind:             types.FluxResourceKindKustomization,
+		Name:             "apps",
+		Namespace:        "flux-system",
+		ReconciliationID: "r3",
+		ObservedAt:       time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{lines[0], lines[1], lines[4]}
+	if len(snippets) != 1 || strings.Join(snippets[0].Lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("lines = %+v, want %q", snippets, want)
+	}
+	if !snippets[0].FromTime.Equal(time.Date(2024, 12, 24, 9, 59, 0, 0, time.UTC)) {
+		t.Errorf("unexpected fromTime %s", snippets[0].FromTime)
+	}
+}
@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/afeldman/fluxbrain/internal/rules"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Source-controller GVRs. OCIRepository and Bucket are still read via v1beta2,
+// which every Flux 2.x release serves.
+var (
+	GitRepositoryGVR  = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}
+	OCIRepositoryGVR  = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Resource: "ocirepositories"}
+	HelmRepositoryGVR = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "helmrepositories"}
+	HelmChartGVR      = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "helmcharts"}
+	BucketGVR         = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1beta2", Resource: "buckets"}
+)
+
+// sourceConditions are the source-controller conditions copied into the context.
+var sourceConditions = []string{"Ready", "FetchFailed", "ArtifactInStorage"}
+
+// SourceKinds maps every supported source kind to its GVR.
+var SourceKinds = map[types.FluxResourceKind]schema.GroupVersionResource{
+	types.FluxResourceKindGitRepository:  GitRepositoryGVR,
+	types.FluxResourceKindOCIRepository:  OCIRepositoryGVR,
+	types.FluxResourceKindHelmRepository: HelmRepositoryGVR,
+	types.FluxResourceKindHelmChart:      HelmChartGVR,
+	types.FluxResourceKindBucket:         BucketGVR,
+}
+
+// SourceCollector emits ErrorContext objects for failing source-controller objects
+// of a single kind, so source outages are reported as their own contexts.
+type SourceCollector struct {
+	Kind      types.FluxResourceKind
+	GVR       schema.GroupVersionResource
+	Cluster   string
+	Namespace string
+	Client    dynamic.Interface
+	// Lister is optional; without it contexts carry no events.
+	Lister EventLister
+	// Rules classifies failing conditions; nil uses rules.Default().
+	Rules *rules.Set
+	// Logs is optional; without it no controller logs are collected.
+	Logs *LogCollector
+}
+
+// NewSourceCollector constructs a collector for one source kind.
+func NewSourceCollector(kind types.FluxResourceKind, cluster, namespace string, client dynamic.Interface, lister EventLister) *SourceCollector {
+	return &SourceCollector{
+		Kind:      kind,
+		GVR:       SourceKinds[kind],
+		Cluster:   cluster,
+		Namespace: namespace,
+		Client:    client,
+		Lister:    lister,
+	}
+}
+
+// NewSourceCollectors returns one SourceCollector per supported source kind.
+// They share lister; pass an EventCache that is reset every cycle, so the
+// kinds of one cycle list events once instead of once per kind.
+func NewSourceCollectors(cluster, namespace string, client dynamic.Interface, lister EventLister) []*SourceCollector {
+	kinds := make([]string, 0, len(SourceKinds))
+	for k := range SourceKinds {
+		kinds = append(kinds, string(k))
+	}
+	sort.Strings(kinds)
+
+	out := make([]*SourceCollector, 0, len(kinds))
+	for _, k := range kinds {
+		out = append(out, NewSourceCollector(types.FluxResourceKind(k), cluster, namespace, client, lister))
+	}
+	return out
+}
+
+// CollectFailedSources lists objects of the collector's kind and returns contexts
+// for those with Ready=False or FetchFailed=True.
+func (c *SourceCollector) CollectFailedSources(ctx context.Context) ([]types.ErrorContext, error) {
+	if c.Client == nil {
+		return nil, errors.New("dynamic client is not configured")
+	}
+	if c.GVR.Resource == "" {
+		return nil, errors.New("unsupported source kind " + string(c.Kind))
+	}
+
+	items, err := listFluxObjects(ctx, c.Client, c.GVR, c.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []K8sEvent
+	if c.Lister != nil && len(items) > 0 {
+		events, err = c.Lister.ListEvents(ctx, c.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ruleSet := ruleSetOr(c.Rules)
+	out := make([]types.ErrorContext, 0)
+	for i := range items {
+		obj := &items[i]
+		conds := conditionsOf(obj, sourceConditions...)
+		fetch, fetchFailed := findCondition(conds, "FetchFailed")
+		if !isNotReady(conds) && !(fetchFailed && fetch.Status == "True") {
+			continue
+		}
+		if !ruleSet.Match(conditionSignal(c.Kind, obj, failureCondition(conds))) {
+			continue
+		}
+		ec := c.contextFor(obj, conds, events)
+		attachLogs(ctx, c.Logs, &ec)
+		out = append(out, ec)
+	}
+
+	sortContexts(out)
+	return out, nil
+}
+
+// failureCondition prefers FetchFailed=True over the Ready summary.
+func failureCondition(conds []types.Condition) types.Condition {
+	failure, ok := findCondition(conds, "FetchFailed")
+	if !ok || failure.Status != "True" {
+		failure, _ = findCondition(conds, conditionReady)
+	}
+	return failure
+}
+
+func (c *SourceCollector) contextFor(obj *unstructured.Unstructured, conds []types.Condition, events []K8sEvent) types.ErrorContext {
+	failure := failureCondition(conds)
+
+	artifact := artifactOf(obj)
+	evs := eventsFor(events, c.Kind, obj.GetNamespace(), obj.GetName())
+	sort.Strings(evs)
+
+	ec := types.ErrorContext{
+		Source:  "flux-status",
+		Cluster: c.Cluster,
+		Resource: types.ResourceRef{
+			Kind:      c.Kind,
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+		},
+		Git:        sourceGitContext(c.Kind, obj),
+		Artifact:   artifact,
+		Conditions: conds,
+		ErrorMsg:   failure.Message,
+		Reason:     failure.Reason,
+		Events:     evs,
+		Timestamp:  failure.LastTransitionTime,
+	}
+	if artifact != nil {
+		ec.Git.Revision = artifact.Revision
+	}
+	return ec
+}
+
+// artifactOf reads status.artifact, or nil if no artifact was ever stored.
+func artifactOf(obj *unstructured.Unstructured) *types.ArtifactContext {
+	revision := nestedString(obj, "status", "artifact", "revision")
+	if revision == "" {
+		return nil
+	}
+	artifact := &types.ArtifactContext{
+		Revision: revision,
+		Digest:   nestedString(obj, "status", "artifact", "digest"),
+		URL:      nestedString(obj, "status", "artifact", "url"),
+	}
+	if ts, err := parseTime(nestedString(obj, "status", "artifact", "lastUpdateTime")); err == nil {
+		artifact.LastUpdateTime = ts
+	}
+	return artifact
+}
+
+// sourceGitContext describes where a source fetches from.
+func sourceGitContext(kind types.FluxResourceKind, obj *unstructured.Unstructured) types.GitContext {
+	if kind == types.FluxResourceKindHelmChart {
+		return types.GitContext{
+			Repository: sourceRefOf(obj),
+			Path:       nestedString(obj, "spec", "chart"),
+		}
+	}
+	if kind == types.FluxResourceKindBucket {
+		return types.GitContext{
+			Repository: nestedString(obj, "spec", "endpoint") + "/" + nestedString(obj, "spec", "bucketName"),
+			Path:       nestedString(obj, "spec", "prefix"),
+		}
+	}
+	return types.GitContext{Repository: nestedString(obj, "spec", "url")}
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func gitRepository(name string, conditions ...interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "GitRepository",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "flux-system"},
+		"spec":       map[string]interface{}{"url": "https://github.com/org/" + name},
+		"status": map[string]interface{}{
+			"artifact": map[string]interface{}{
+				"revision":       "main@sha1:abc123",
+				"digest":         "sha256:deadbeef",
+				"lastUpdateTime": "2024-12-24T09:00:00Z",
+			},
+			"conditions": conditions,
+		},
+	}}
+}
+
+func TestSourceCollectorGitRepository(t *testing.T) {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for kind, gvr := range SourceKinds {
+		listKinds[gvr] = string(kind) + "List"
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		gitRepository("broken",
+			map[string]interface{}{"type": "Ready", "status": "False", "reason": "GitOperationFailed", "message": "failed to checkout", "lastTransitionTime": "2024-12-24T10:00:00Z"},
+			map[string]interface{}{"type": "FetchFailed", "status": "True", "reason": "GitOperationFailed", "message": "failed to checkout and determine revision: authentication required", "lastTransitionTime": "2024-12-24T10:00:00Z"},
+			map[string]interface{}{"type": "ArtifactInStorage", "status": "True", "reason": "Succeeded"},
+		),
+		gitRepository("healthy",
+			map[string]interface{}{"type": "Ready", "status": "True", "reason": "Succeeded"},
+		),
+	)
+
+	collectors := NewSourceCollectors("prod", "flux-system", client, staticLister{})
+	if len(collectors) != len(SourceKinds) {
+		t.Fatalf("expected one collector per source kind, got %d", len(collectors))
+	}
+
+	var got []types.ErrorContext
+	for _, c := range collectors {
+		ecs, err := c.CollectErrors(context.Background())
+		if err != nil {
+			t.Fatalf("%s collect failed: %v", c.Kind, err)
+		}
+		got = append(got, ecs...)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected one failing source, got %d: %+v", len(got), got)
+	}
+	ec := got[0]
+	if ec.Resource.Kind != types.FluxResourceKindGitRepository || ec.Resource.Name != "broken" {
+		t.Errorf("unexpected resource: %+v", ec.Resource)
+	}
+	if ec.Reason != "GitOperationFailed" || ec.ErrorMsg != "failed to checkout and determine revision: authentication required" {
+		t.Errorf("FetchFailed condition should drive reason/message: %q %q", ec.Reason, ec.ErrorMsg)
+	}
+	if ec.Git.Repository != "https://github.com/org/broken" || ec.Git.Revision != "main@sha1:abc123" {
+		t.Errorf("unexpected git context: %+v", ec.Git)
+	}
+	if ec.Artifact == nil || ec.Artifact.Digest != "sha256:deadbeef" {
+		t.Errorf("artifact not mapped: %+v", ec.Artifact)
+	}
+	if len(ec.Conditions) != 3 {
+		t.Errorf("expected Ready, FetchFailed and ArtifactInStorage, got %+v", ec.Conditions)
+	}
+}
+
+func TestSourceCollectorsShareOneEventListPerCycle(t *testing.T) {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for kind, gvr := range SourceKinds {
+		listKinds[gvr] = string(kind) + "List"
+	}
+	broken := map[string]interface{}{"type": "Ready", "status": "False", "reason": "GitOperationFailed"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		gitRepository("a", broken),
+		gitRepository("b", broken),
+	)
+	inner := &countingLister{}
+	cache := NewEventCache(inner)
+	collectors := NewSourceCollectors("prod", "flux-system", client, cache)
+
+	for cycle := 1; cycle <= 2; cycle++ {
+		cache.Reset()
+		for _, c := range collectors {
+			if _, err := c.CollectErrors(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if inner.calls != cycle {
+			t.Fatalf("cycle %d: events listed %d times, want %d", cycle, inner.calls, cycle)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// KustomizationGVR addresses kustomize-controller's Kustomization API.
+var KustomizationGVR = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+
+// FluxKinds lists every Flux kind fluxbrain can read.
+var FluxKinds = []types.FluxResourceKind{
+	types.FluxResourceKindKustomization,
+	types.FluxResourceKindHelmRelease,
+	types.FluxResourceKindGitRepository,
+	types.FluxResourceKindOCIRepository,
+	types.FluxResourceKindHelmRepository,
+	types.FluxResourceKindHelmChart,
+	types.FluxResourceKindBucket,
+}
+
+// FluxKindGVR returns the GVR used to read a Flux kind.
+func FluxKindGVR(kind types.FluxResourceKind) (schema.GroupVersionResource, bool) {
+	switch kind {
+	case types.FluxResourceKindKustomization:
+		return KustomizationGVR, true
+	case types.FluxResourceKindHelmRelease:
+		return HelmReleaseGVR, true
+	}
+	gvr, ok := SourceKinds[kind]
+	return gvr, ok
+}
+
+// StatusCollector implements types.Collector on top of the dynamic client.
+// It reads a Flux object's status conditions, revisions and sourceRef together
+// with the Events recorded for it.
+type StatusCollector struct {
+	Cluster string
+	Client  dynamic.Interface
+	// Lister is optional; without it no events are collected.
+	Lister EventLister
+	// Logs is optional; without it no controller logs are collected.
+	Logs *LogCollector
+}
+
+// NewStatusCollector constructs a status collector for a cluster.
+func NewStatusCollector(cluster string, client dynamic.Interface, lister EventLister) *StatusCollector {
+	return &StatusCollector{
+		Cluster: cluster,
+		Client:  client,
+		Lister:  lister,
+	}
+}
+
+// Collect implements types.Collector.
+func (c *StatusCollector) Collect(ctx context.Context, selector types.ResourceSelector) (types.CollectedSignals, error) {
+	if c.Client == nil {
+		return types.CollectedSignals{}, errors.New("dynamic client is not configured")
+	}
+	gvr, ok := FluxKindGVR(selector.Kind)
+	if !ok {
+		return types.CollectedSignals{}, fmt.Errorf("unsupported flux kind %q", selector.Kind)
+	}
+
+	obj, err := c.Client.Resource(gvr).Namespace(selector.Namespace).Get(ctx, selector.Name, metav1.GetOptions{})
+	if err != nil {
+		return types.CollectedSignals{}, fmt.Errorf("get %s %s/%s: %w", selector.Kind, selector.Namespace, selector.Name, err)
+	}
+
+	cluster := selector.Cluster
+	if cluster == "" {
+		cluster = c.Cluster
+	}
+	signals := types.CollectedSignals{Status: c.statusOf(ctx, selector.Kind, cluster, obj)}
+
+	if c.Lister != nil {
+		events, err := c.Lister.ListEvents(ctx, selector.Namespace)
+		if err != nil {
+			return types.CollectedSignals{}, err
+		}
+		for _, ev := range events {
+			if !strings.EqualFold(ev.InvolvedKind, string(selector.Kind)) || ev.Namespace != selector.Namespace || ev.Name != selector.Name {
+				continue
+			}
+			signals.Events = append(signals.Events, types.FluxEvent{
+				Kind:      selector.Kind,
+				Name:      ev.Name,
+				Namespace: ev.Namespace,
+				Type:      ev.Type,
+				Reason:    ev.Reason,
+				Message:   ev.Message,
+				Timestamp: ev.Timestamp,
+			})
+		}
+	}
+
+	if c.Logs != nil {
+		// logs are supplementary; a failing log API must not block the context
+		logs, err := c.Logs.CollectLogs(ctx, signals.Status)
+		if err != nil {
+			slog.Warn("controller log collection failed", "kind", string(selector.Kind), "namespace", selector.Namespace, "name", selector.Name, "error", err)
+		}
+		signals.Logs = logs
+	}
+	return signals, nil
+}
+
+func (c *StatusCollector) statusOf(ctx context.Context, kind types.FluxResourceKind, cluster string, obj *unstructured.Unstructured) types.ResourceStatus {
+	conds := conditionsOf(obj)
+	ready, _ := findCondition(conds, conditionReady)
+
+	status := types.ResourceStatus{
+		Kind:                   kind,
+		Name:                   obj.GetName(),
+		Namespace:              obj.GetNamespace(),
+		Cluster:                cluster,
+		Ready:                  ready.Status == "True",
+		Status:                 ready.Status,
+		Reason:                 ready.Reason,
+		Message:                ready.Message,
+		LastHandledRevision:    nestedString(obj, "status", "lastAppliedRevision"),
+		SourcePath:             nestedString(obj, "spec", "path"),
+		SourceRevision:         nestedString(obj, "status", "lastAttemptedRevision"),
+		SourceRef:              sourceRefOf(obj),
+		LastHandledReconcileAt: nestedString(obj, "status", "lastHandledReconcileAt"),
+		Conditions:             conds,
+		ObservedAt:             ready.LastTransitionTime,
+	}
+
+	switch {
+	case kind == types.FluxResourceKindHelmRelease:
+		status.SourceRef = helmSourceRef(obj)
+	case kind != types.FluxResourceKindKustomization:
+		// sources describe themselves
+		status.SourceRepository = sourceGitContext(kind, obj).Repository
+		status.LastHandledRevision = nestedString(obj, "status", "artifact", "revision")
+	}
+	if status.SourceRevision == "" {
+		status.SourceRevision = status.LastHandledRevision
+	}
+	if status.SourceRepository == "" {
+		status.SourceRepository = c.sourceURL(ctx, status.SourceRef)
+	}
+	return status
+}
+
+// sourceURL resolves a Kind/namespace/name source reference to its URL.
+// It falls back to the reference itself when the source cannot be read.
+func (c *StatusCollector) sourceURL(ctx context.Context, ref string) string {
+	parts := strings.SplitN(ref, "/", 3)
+	if len(parts) != 3 {
+		return ref
+	}
+	kind := types.FluxResourceKind(parts[0])
+	gvr, ok := SourceKinds[kind]
+	if !ok {
+		return ref
+	}
+	src, err := c.Client.Resource(gvr).Namespace(parts[1]).Get(ctx, parts[2], metav1.GetOptions{})
+	if err != nil {
+		return ref
+	}
+	if repo := sourceGitContext(kind, src).Repository; repo != "" {
+		return repo
+	}
+	return ref
+}
+
+// EventCache caches ListEvents results per namespace until Reset, so the
+// collectors of one cycle share a single event list per namespace.
+type EventCache struct {
+	inner EventLister
+
+	mu    sync.Mutex
+	cache map[string][]K8sEvent
+}
+
+// NewEventCache wraps inner.
+func NewEventCache(inner EventLister) *EventCache {
+	return &EventCache{inner: inner, cache: map[string][]K8sEvent{}}
+}
+
+// ListEvents implements EventLister. Errors are not cached.
+func (m *EventCache) ListEvents(ctx context.Context, namespace string) ([]K8sEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if events, ok := m.cache[namespace]; ok {
+		return events, nil
+	}
+	events, err := m.inner.ListEvents(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	m.cache[namespace] = events
+	return events, nil
+}
+
+// Reset drops the cached lists, e.g. at the start of a cycle.
+func (m *EventCache) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = map[string][]K8sEvent{}
+}
+
+// Ready reports whether the resource has Ready=True. A deleted resource counts as
+// ready, because there is nothing left to fail.
+func (c *StatusCollector) Ready(ctx context.Context, ref types.ResourceRef) (bool, error) {
+	if c.Client == nil {
+		return false, errors.New("dynamic client is not configured")
+	}
+	gvr, ok := FluxKindGVR(ref.Kind)
+	if !ok {
+		return false, fmt.Errorf("unsupported flux kind %q", ref.Kind)
+	}
+	obj, err := c.Client.Resource(gvr).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ready, ok := findCondition(conditionsOf(obj, conditionReady), conditionReady)
+	return ok && ready.Status == "True", nil
+}
@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func fluxListKinds() map[schema.GroupVersionResource]string {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, kind := range FluxKinds {
+		gvr, _ := FluxKindGVR(kind)
+		listKinds[gvr] = string(kind) + "List"
+	}
+	return listKinds
+}
+
+func kustomization(name, readyStatus string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "flux-system"},
+		"spec": map[string]interface{}{
+			"path":      "./clusters/prod",
+			"sourceRef": map[string]interface{}{"kind": "GitRepository", "name": "fleet"},
+		},
+		"status": map[string]interface{}{
+			"lastAppliedRevision":    "main@sha1:aaa",
+			"lastAttemptedRevision":  "main@sha1:bbb",
+			"lastHandledReconcileAt": "2024-12-24T09:59:00Z",
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"type": "Ready", "status": readyStatus, "reason": "BuildFailed",
+					"message":            "kustomize build failed: accumulating resources",
+					"lastTransitionTime": "2024-12-24T10:00:00Z",
+				},
+			},
+		},
+	}}
+}
+
+func TestStatusCollectorCollect(t *testing.T) {
+	fleet := gitRepository("fleet", map[string]interface{}{"type": "Ready", "status": "True"})
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), fluxListKinds(),
+		kustomization("apps", "False"), fleet)
+	events := staticLister{
+		{InvolvedKind: "Kustomization", Name: "apps", Namespace: "flux-system", Type: "Warning", Reason: "BuildFailed", Message: "accumulating resources"},
+		{InvolvedKind: "Kustomization", Name: "other", Namespace: "flux-system", Type: "Warning", Reason: "BuildFailed"},
+	}
+
+	c := NewStatusCollector("prod", client, events)
+	signals, err := c.Collect(context.Background(), types.ResourceSelector{
+		Kind:      types.FluxResourceKindKustomization,
+		Namespace: "flux-system",
+		Name:      "apps",
+	})
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+
+	st := signals.Status
+	if st.Cluster != "prod" || st.Ready || st.Reason != "BuildFailed" {
+		t.Errorf("unexpected ready facts: %+v", st)
+	}
+	if st.LastHandledRevision != "main@sha1:aaa" || st.SourceRevision != "main@sha1:bbb" || st.SourcePath != "./clusters/prod" {
+		t.Errorf("unexpected revisions: %+v", st)
+	}
+	if st.SourceRef != "GitRepository/flux-system/fleet" || st.SourceRepository != "https://github.com/org/fleet" {
+		t.Errorf("sourceRef not resolved: %q %q", st.SourceRef, st.SourceRepository)
+	}
+	if st.LastHandledReconcileAt != "2024-12-24T09:59:00Z" || len(st.Conditions) != 1 {
+		t.Errorf("unexpected reconcile facts: %+v", st)
+	}
+	if !st.ObservedAt.Equal(time.Date(2024, 12, 24, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected observedAt: %s", st.ObservedAt)
+	}
+	if len(signals.Events) != 1 || signals.Events[0].Name != "apps" {
+		t.Errorf("expected only events of apps, got %+v", signals.Events)
+	}
+}
+
+func TestStatusErrorCollectorListsNotReady(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), fluxListKinds(),
+		kustomization("apps", "False"),
+		kustomization("infra", "True"),
+		gitRepository("fleet", map[string]interface{}{"type": "Ready", "status": "True"}),
+	)
+
+	c := NewStatusErrorCollector(NewStatusCollector("prod", client, staticLister{}), "flux-system")
+	got, err := c.CollectErrors(context.Background())
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one not-Ready object, got %d", len(got))
+	}
+	ec := got[0]
+	if ec.Source != "flux-status" || ec.Resource.Name != "apps" || ec.Git.Revision != "main@sha1:bbb" || ec.Git.Path != "./clusters/prod" {
+		t.Errorf("unexpected context: %+v", ec)
+	}
+}
+
+// countingLister counts ListEvents calls.
+type countingLister struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingLister) ListEvents(context.Context, string) ([]K8sEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	return nil, nil
+}
+
+func TestEventCacheListsOncePerNamespaceUntilReset(t *testing.T) {
+	inner := &countingLister{}
+	cache := NewEventCache(inner)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := cache.ListEvents(ctx, "flux-system"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("calls = %d, want 1", inner.calls)
+	}
+	cache.Reset()
+	if _, err := cache.ListEvents(ctx, "flux-system"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("calls after Reset = %d, want 2", inner.calls)
+	}
+}
@@ -3,38 +3,149 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"time"
 )
 
+// Run modes supported by FLUXBRAIN_RUN_MODE.
+const (
+	RunModeContinuous = "continuous"
+	RunModeOnce       = "once"
+)
+
+// State backends supported by FLUXBRAIN_STATE_BACKEND.
+const (
+	StateBackendMemory    = "memory"
+	StateBackendRedis     = "redis"
+	StateBackendFile      = "file"
+	StateBackendConfigMap = "configmap"
+)
+
 // Config holds runtime configuration loaded from environment variables.
 type Config struct {
 	ClusterName              string
+	RunMode                  string
 	FluxNamespace            string
+	Kubeconfig               string
+	KubeContext              string
+	EventAPI                 string
+	WatchEvents              bool
+	StatusCollector          bool
+	RulesFile                string
 	CollectControllerLogs    bool
+	ControllerLogWindow      time.Duration
 	NotificationSlackWebhook string
 	NotificationWebhookURL   string
 	GitHubOwner              string
 	GitHubRepo               string
 	GitHubToken              string
 	RequeueInterval          time.Duration
+	Concurrency              int
+	ItemTimeout              time.Duration
+	NotifyTimeout            time.Duration
+	Strictness               string
+	ReportFile               string
+	StateBackend             string
+	BackoffStrategy          string
+	BackoffBase              time.Duration
+	BackoffMax               time.Duration
+	BackoffMultiplier        float64
+	BackoffJitter            string
+	BackoffFile              string
+	Fingerprint              string
+	ReminderIntervals        string
+	ReminderEscalateAfter    int
+	FlapWindow               time.Duration
+	FlapThreshold            int
+	StatePath                string
+	StateRetention           time.Duration
+	HistoryRetention         time.Duration
+	StateConfigMap           string
+	RedisAddr                string
+	RedisPassword            string
+	RedisDB                  int
+	RedisPrefix              string
+	RedisTimeout             time.Duration
+	MetricsAddr              string
+	ProbeAddr                string
+	APIAddr                  string
+	APIToken                 string
+	LeaderElection           bool
+	LeaderElectionNamespace  string
+	LeaseName                string
+	LeaseDuration            time.Duration
+	LeaseRenewDeadline       time.Duration
+	LeaseRetryPeriod         time.Duration
+	LivenessMissedCycles     int
 	LogLevel                 string
+	LogFormat                string
 }
 
 // Load reads environment variables into a Config instance.
 func Load() (Config, error) {
 	cfg := Config{
 		ClusterName:              getenv("FLUXBRAIN_CLUSTER", ""),
+		RunMode:                  getenv("FLUXBRAIN_RUN_MODE", RunModeContinuous),
 		FluxNamespace:            getenv("FLUXBRAIN_FLUX_NAMESPACE", "flux-system"),
+		Kubeconfig:               getenv("FLUXBRAIN_KUBECONFIG", ""),
+		KubeContext:              getenv("FLUXBRAIN_KUBE_CONTEXT", ""),
+		EventAPI:                 getenv("FLUXBRAIN_EVENT_API", "core/v1"),
+		WatchEvents:              getenvBool("FLUXBRAIN_WATCH_EVENTS", true),
+		StatusCollector:          getenvBool("FLUXBRAIN_STATUS_COLLECTOR", false),
+		RulesFile:                getenv("FLUXBRAIN_RULES_FILE", ""),
 		CollectControllerLogs:    getenvBool("FLUXBRAIN_COLLECT_LOGS", false),
+		ControllerLogWindow:      getenvDuration("FLUXBRAIN_LOG_WINDOW", 5*time.Minute),
 		NotificationSlackWebhook: getenv("FLUXBRAIN_SLACK_WEBHOOK", ""),
 		NotificationWebhookURL:   getenv("FLUXBRAIN_WEBHOOK_URL", ""),
 		GitHubOwner:              getenv("FLUXBRAIN_GITHUB_OWNER", ""),
 		GitHubRepo:               getenv("FLUXBRAIN_GITHUB_REPO", ""),
 		GitHubToken:              getenv("FLUXBRAIN_GITHUB_TOKEN", ""),
 		RequeueInterval:          getenvDuration("FLUXBRAIN_REQUEUE_INTERVAL", 5*time.Minute),
+		Concurrency:              getenvInt("FLUXBRAIN_CONCURRENCY", 4),
+		ItemTimeout:              getenvDuration("FLUXBRAIN_ITEM_TIMEOUT", 2*time.Minute),
+		NotifyTimeout:            getenvDuration("FLUXBRAIN_NOTIFY_TIMEOUT", 30*time.Second),
+		Strictness:               getenv("FLUXBRAIN_STRICTNESS", "collectors"),
+		ReportFile:               getenv("FLUXBRAIN_REPORT_FILE", ""),
+		StateBackend:             getenv("FLUXBRAIN_STATE_BACKEND", StateBackendMemory),
+		BackoffStrategy:          getenv("FLUXBRAIN_BACKOFF_STRATEGY", "exponential"),
+		BackoffBase:              getenvDuration("FLUXBRAIN_BACKOFF_BASE", 30*time.Second),
+		BackoffMax:               getenvDuration("FLUXBRAIN_BACKOFF_MAX", time.Hour),
+		BackoffMultiplier:        getenvFloat("FLUXBRAIN_BACKOFF_MULTIPLIER", 2),
+		BackoffJitter:            getenv("FLUXBRAIN_BACKOFF_JITTER", "full"),
+		BackoffFile:              getenv("FLUXBRAIN_BACKOFF_FILE", ""),
+		Fingerprint:              getenv("FLUXBRAIN_FINGERPRINT", "revision"),
+		ReminderIntervals:        getenv("FLUXBRAIN_REMINDER_INTERVALS", "1h,4h,24h"),
+		ReminderEscalateAfter:    getenvInt("FLUXBRAIN_REMINDER_ESCALATE_AFTER", 3),
+		FlapWindow:               getenvDuration("FLUXBRAIN_FLAP_WINDOW", time.Hour),
+		FlapThreshold:            getenvInt("FLUXBRAIN_FLAP_THRESHOLD", 6),
+		StatePath:                getenv("FLUXBRAIN_STATE_PATH", "/var/lib/fluxbrain/state.db"),
+		StateRetention:           getenvDuration("FLUXBRAIN_STATE_RETENTION", 24*time.Hour),
+		HistoryRetention:         getenvDuration("FLUXBRAIN_HISTORY_RETENTION", 7*24*time.Hour),
+		StateConfigMap:           getenv("FLUXBRAIN_STATE_CONFIGMAP", "fluxbrain-state"),
+		RedisAddr:                getenv("FLUXBRAIN_REDIS_ADDR", "localhost:6379"),
+		RedisPassword:            getenv("FLUXBRAIN_REDIS_PASSWORD", ""),
+		RedisDB:                  getenvInt("FLUXBRAIN_REDIS_DB", 0),
+		RedisPrefix:              getenv("FLUXBRAIN_REDIS_PREFIX", "fluxbrain"),
+		RedisTimeout:             getenvDuration("FLUXBRAIN_REDIS_TIMEOUT", 2*time.Second),
+		MetricsAddr:              getenv("FLUXBRAIN_METRICS_ADDR", ":8080"),
+		ProbeAddr:                getenv("FLUXBRAIN_PROBE_ADDR", ":8081"),
+		APIAddr:                  getenv("FLUXBRAIN_API_ADDR", "127.0.0.1:8082"),
+		APIToken:                 getenv("FLUXBRAIN_API_TOKEN", ""),
+		LivenessMissedCycles:     getenvInt("FLUXBRAIN_LIVENESS_MISSED_CYCLES", 3),
+		LeaderElection:           getenvBool("FLUXBRAIN_LEADER_ELECTION", false),
+		LeaderElectionNamespace:  getenv("FLUXBRAIN_LEADER_ELECTION_NAMESPACE", ""),
+		LeaseName:                getenv("FLUXBRAIN_LEASE_NAME", "fluxbrain"),
+		LeaseDuration:            getenvDuration("FLUXBRAIN_LEASE_DURATION", 15*time.Second),
+		LeaseRenewDeadline:       getenvDuration("FLUXBRAIN_LEASE_RENEW_DEADLINE", 10*time.Second),
+		LeaseRetryPeriod:         getenvDuration("FLUXBRAIN_LEASE_RETRY_PERIOD", 2*time.Second),
 		LogLevel:                 getenv("FLUXBRAIN_LOG_LEVEL", "info"),
+		LogFormat:                getenv("FLUXBRAIN_LOG_FORMAT", "text"),
+	}
+
+	if cfg.LeaderElectionNamespace == "" {
+		cfg.LeaderElectionNamespace = cfg.FluxNamespace
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -47,10 +158,63 @@ func (c Config) validate() error {
 	if c.ClusterName == "" {
 		return errors.New("FLUXBRAIN_CLUSTER is required")
 	}
+	if c.RunMode != RunModeContinuous && c.RunMode != RunModeOnce {
+		return fmt.Errorf("invalid FLUXBRAIN_RUN_MODE %q (expected %q or %q)", c.RunMode, RunModeContinuous, RunModeOnce)
+	}
+	if c.EventAPI != "core/v1" && c.EventAPI != "events.k8s.io/v1" {
+		return fmt.Errorf("invalid FLUXBRAIN_EVENT_API %q (expected core/v1 or events.k8s.io/v1)", c.EventAPI)
+	}
+	switch c.StateBackend {
+	case StateBackendMemory, StateBackendRedis, StateBackendFile, StateBackendConfigMap:
+	default:
+		return fmt.Errorf("invalid FLUXBRAIN_STATE_BACKEND %q (expected %q, %q, %q or %q)", c.StateBackend, StateBackendMemory, StateBackendRedis, StateBackendFile, StateBackendConfigMap)
+	}
+	if c.Concurrency < 1 {
+		return errors.New("FLUXBRAIN_CONCURRENCY must be at least 1")
+	}
+	if c.RunMode == RunModeContinuous && c.RequeueInterval <= 0 {
+		return errors.New("FLUXBRAIN_REQUEUE_INTERVAL must be positive")
+	}
+	// a new leader must see the silences, notifications and open failures of the old one
+	if c.LeaderElection && (c.StateBackend == StateBackendMemory || c.StateBackend == StateBackendFile) {
+		return fmt.Errorf("FLUXBRAIN_LEADER_ELECTION needs a state backend shared by all replicas (%q or %q), not %q", StateBackendRedis, StateBackendConfigMap, c.StateBackend)
+	}
+	if c.LeaderElection && c.LeaseDuration <= c.LeaseRenewDeadline {
+		return errors.New("FLUXBRAIN_LEASE_DURATION must be greater than FLUXBRAIN_LEASE_RENEW_DEADLINE")
+	}
+	if c.LeaderElection && c.LeaseRenewDeadline <= c.LeaseRetryPeriod {
+		return errors.New("FLUXBRAIN_LEASE_RENEW_DEADLINE must be greater than FLUXBRAIN_LEASE_RETRY_PERIOD")
+	}
+	if c.ReminderEscalateAfter < 0 {
+		return errors.New("FLUXBRAIN_REMINDER_ESCALATE_AFTER must not be negative")
+	}
+	if c.FlapWindow < 0 || c.FlapThreshold < 0 {
+		return errors.New("FLUXBRAIN_FLAP_WINDOW and FLUXBRAIN_FLAP_THRESHOLD must not be negative")
+	}
+	if c.LivenessMissedCycles < 1 {
+		return errors.New("FLUXBRAIN_LIVENESS_MISSED_CYCLES must be at least 1")
+	}
+	// anyone reaching the API can silence alerts
+	if c.APIAddr != "" && c.APIToken == "" && !loopback(c.APIAddr) {
+		return fmt.Errorf("FLUXBRAIN_API_ADDR %q is reachable beyond localhost and requires FLUXBRAIN_API_TOKEN", c.APIAddr)
+	}
 	// Note: Errorbrain-Integration ist optional bis Library verfügbar ist
 	return nil
 }
 
+// loopback reports whether the listen address addr only accepts local connections.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func getenv(key, def string) string {
 	if v, ok := os.LookupEnv(key); ok {
 		return v
@@ -68,6 +232,28 @@ func getenvBool(key string, def bool) bool {
 	return def
 }
 
+func getenvInt(key string, def int) int {
+	if v, ok := os.LookupEnv(key); ok {
+		parsed, err := strconv.Atoi(v)
+		if err == nil {
+			return parsed
+		}
+		fmt.Fprintf(os.Stderr, "invalid integer for %s: %v\n", key, err)
+	}
+	return def
+}
+
+func getenvFloat(key string, def float64) float64 {
+	if v, ok := os.LookupEnv(key); ok {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return parsed
+		}
+		fmt.Fprintf(os.Stderr, "invalid number for %s: %v\n", key, err)
+	}
+	return def
+}
+
 func getenvDuration(key string, def time.Duration) time.Duration {
 	if v, ok := os.LookupEnv(key); ok {
 		parsed, err := time.ParseDuration(v)
@@ -39,16 +39,7 @@ func BuildContextFromSignals(signals types.CollectedSignals) types.ErrorContext
 		events = append(events, formatEvent(ev))
 	}
 
-	logSnippets := make([]string, 0, len(signals.Logs))
-	sort.Slice(signals.Logs, func(i, j int) bool {
-		if signals.Logs[i].Source == signals.Logs[j].Source {
-			return signals.Logs[i].FromTime.Before(signals.Logs[j].FromTime)
-		}
-		return signals.Logs[i].Source < signals.Logs[j].Source
-	})
-	for _, l := range signals.Logs {
-		logSnippets = append(logSnippets, formatLog(l))
-	}
+	logSnippets := FormatLogs(signals.Logs)
 
 	status := signals.Status
 	git := types.GitContext{
@@ -66,6 +57,7 @@ func BuildContextFromSignals(signals types.CollectedSignals) types.ErrorContext
 			Namespace: status.Namespace,
 		},
 		Git:         git,
+		Conditions:  status.Conditions,
 		ErrorMsg:    status.Message,
 		Reason:      status.Reason,
 		Events:      events,
@@ -91,6 +83,22 @@ func formatEvent(ev types.FluxEvent) string {
 	return ts + " | " + ev.Reason + " | " + ev.Message
 }
 
+// FormatLogs orders log snippets by source and time and renders them as
+// ErrorContext.LogSnippets.
+func FormatLogs(logs []types.LogSnippet) []string {
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].Source == logs[j].Source {
+			return logs[i].FromTime.Before(logs[j].FromTime)
+		}
+		return logs[i].Source < logs[j].Source
+	})
+	out := make([]string, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, formatLog(l))
+	}
+	return out
+}
+
 func formatLog(sn types.LogSnippet) string {
 	ts := sn.FromTime.UTC().Format(time.RFC3339)
 	return sn.Source + "@" + ts + " -> " + joinLines(sn.Lines)
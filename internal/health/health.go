@@ -0,0 +1,164 @@
+// Package health serves liveness and readiness probes for the continuous mode.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// DefaultMaxMissed is the number of intervals without a completed cycle after
+// which the process is considered stuck.
+const DefaultMaxMissed = 3
+
+// DefaultCheckTimeout bounds each readiness check.
+const DefaultCheckTimeout = 5 * time.Second
+
+// Check probes one dependency; a nil error means it is reachable.
+type Check func(ctx context.Context) error
+
+// Probe tracks reconciliation progress for /healthz and runs dependency checks for /readyz.
+type Probe struct {
+	// Interval is the expected time between completed cycles.
+	Interval time.Duration
+	// MaxMissed is how many intervals may pass without a completed cycle.
+	MaxMissed int
+	// CheckTimeout bounds each readiness check (0 = none).
+	CheckTimeout time.Duration
+	// Now returns the current time; tests inject a fake clock.
+	Now func() time.Time
+	// Standby reports that the runner is intentionally idle, e.g. a leader
+	// election follower. Liveness passes and the grace period restarts.
+	Standby func() bool
+
+	mu     sync.Mutex
+	checks map[string]Check
+	last   time.Time
+}
+
+// NewProbe creates a probe that expects a completed cycle every interval.
+// The grace period starts now.
+func NewProbe(interval time.Duration, maxMissed int) *Probe {
+	p := &Probe{
+		Interval:     interval,
+		MaxMissed:    maxMissed,
+		CheckTimeout: DefaultCheckTimeout,
+		Now:          time.Now,
+		checks:       make(map[string]Check),
+	}
+	p.last = p.now()
+	return p
+}
+
+// AddCheck registers a readiness check under name.
+func (p *Probe) AddCheck(name string, check Check) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.checks == nil {
+		p.checks = make(map[string]Check)
+	}
+	p.checks[name] = check
+}
+
+// CycleCompleted records that a RunOnce finished, successful or not.
+// A failing cycle still proves the loop is not stuck.
+func (p *Probe) CycleCompleted(error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last = p.now()
+}
+
+// Live returns an error when no cycle completed within MaxMissed intervals.
+func (p *Probe) Live() error {
+	p.mu.Lock()
+	if p.Standby != nil && p.Standby() {
+		p.last = p.now()
+		p.mu.Unlock()
+		return nil
+	}
+	last := p.last
+	p.mu.Unlock()
+
+	maxMissed := p.MaxMissed
+	if maxMissed < 1 {
+		maxMissed = DefaultMaxMissed
+	}
+	limit := time.Duration(maxMissed) * p.Interval
+	if since := p.now().Sub(last); p.Interval > 0 && since > limit {
+		return fmt.Errorf("no reconciliation cycle completed for %s (limit %s)", since.Round(time.Second), limit)
+	}
+	return nil
+}
+
+// Ready runs all checks and returns an error naming every failing one.
+func (p *Probe) Ready(ctx context.Context) error {
+	p.mu.Lock()
+	names := make([]string, 0, len(p.checks))
+	checks := make(map[string]Check, len(p.checks))
+	for name, check := range p.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	p.mu.Unlock()
+	sort.Strings(names)
+
+	var failed []string
+	for _, name := range names {
+		cctx, cancel := ctx, context.CancelFunc(func() {})
+		if p.CheckTimeout > 0 {
+			cctx, cancel = context.WithTimeout(ctx, p.CheckTimeout)
+		}
+		err := checks[name](cctx)
+		cancel()
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("not ready: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Handler serves /healthz (liveness) and /readyz (readiness).
+func (p *Probe) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		respond(w, p.Live())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, p.Ready(r.Context()))
+	})
+	return mux
+}
+
+func respond(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (p *Probe) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+// APIServer checks that the Kubernetes API server answers a version request.
+// The request carries ctx, so a check that times out is canceled with it.
+func APIServer(client rest.Interface) Check {
+	return func(ctx context.Context) error {
+		return client.Get().AbsPath("/version").Do(ctx).Error()
+	}
+}
@@ -0,0 +1,157 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestProbe(c *clock) *Probe {
+	p := &Probe{Interval: time.Minute, MaxMissed: 3, Now: c.now}
+	p.CycleCompleted(nil) // start of grace period
+	return p
+}
+
+func TestLivenessFailsAfterMissedCycles(t *testing.T) {
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p := newTestProbe(c)
+
+	c.advance(3 * time.Minute)
+	if err := p.Live(); err != nil {
+		t.Fatalf("live within limit: %v", err)
+	}
+
+	c.advance(time.Second)
+	if err := p.Live(); err == nil {
+		t.Fatal("expected liveness failure after 3 missed intervals")
+	}
+
+	p.CycleCompleted(errors.New("collector failed"))
+	if err := p.Live(); err != nil {
+		t.Fatalf("a completed (failed) cycle must restore liveness: %v", err)
+	}
+}
+
+func TestLivenessPassesInStandby(t *testing.T) {
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p := newTestProbe(c)
+	standby := true
+	p.Standby = func() bool { return standby }
+
+	c.advance(time.Hour)
+	if err := p.Live(); err != nil {
+		t.Fatalf("standby must be live: %v", err)
+	}
+
+	// taking over restarts the grace period
+	standby = false
+	c.advance(2 * time.Minute)
+	if err := p.Live(); err != nil {
+		t.Fatalf("live within grace after takeover: %v", err)
+	}
+	c.advance(2 * time.Minute)
+	if err := p.Live(); err == nil {
+		t.Fatal("expected liveness failure after takeover without cycles")
+	}
+}
+
+func TestReadinessReportsFailingChecks(t *testing.T) {
+	c := &clock{t: time.Now()}
+	p := newTestProbe(c)
+	apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major":"1","minor":"30"}`))
+	}))
+	defer apiserver.Close()
+	p.AddCheck("apiserver", APIServer(restClient(t, apiserver.URL)))
+	p.AddCheck("state", func(context.Context) error { return errors.New("connection refused") })
+
+	err := p.Ready(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "state: connection refused") {
+		t.Fatalf("Ready() = %v, want state failure", err)
+	}
+	if strings.Contains(err.Error(), "apiserver") {
+		t.Fatalf("apiserver check should pass: %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := &clock{t: time.Now()}
+	p := newTestProbe(c)
+	ready := true
+	p.AddCheck("state", func(context.Context) error {
+		if !ready {
+			return errors.New("down")
+		}
+		return nil
+	})
+	srv := httptest.NewServer(p.Handler())
+	defer srv.Close()
+
+	status := func(path string) int {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := status("/healthz"); got != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", got)
+	}
+	if got := status("/readyz"); got != http.StatusOK {
+		t.Errorf("/readyz = %d, want 200", got)
+	}
+
+	ready = false
+	c.advance(time.Hour)
+	if got := status("/healthz"); got != http.StatusServiceUnavailable {
+		t.Errorf("/healthz = %d, want 503", got)
+	}
+	if got := status("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("/readyz = %d, want 503", got)
+	}
+}
+
+func TestAPIServerCheckCancelsTheRequestOnTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+	apiserver := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer apiserver.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := APIServer(restClient(t, apiserver.URL))(ctx); err == nil {
+		t.Fatal("expected the check to fail when the API server does not answer")
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request should be canceled with the check")
+	}
+}
+
+// restClient returns the discovery REST client of a clientset for host.
+func restClient(t *testing.T, host string) rest.Interface {
+	t.Helper()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientset.Discovery().RESTClient()
+}
@@ -0,0 +1,159 @@
+// Package leader coordinates fluxbrain replicas through a coordination.k8s.io Lease.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Lease timing defaults, matching the usual controller-runtime values.
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// Elector runs a function only while this replica holds the Lease.
+// Losing the Lease cancels the function; the replica then campaigns again,
+// so a follower takes over within about one LeaseDuration.
+type Elector struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	// Identity must be unique per replica; the pod name is a good choice.
+	Identity      string
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	leading atomic.Bool
+}
+
+// NewElector creates an Elector for the Lease namespace/name with default timings.
+func NewElector(client kubernetes.Interface, namespace, name, identity string) *Elector {
+	return &Elector{
+		Client:        client,
+		Namespace:     namespace,
+		Name:          name,
+		Identity:      identity,
+		LeaseDuration: DefaultLeaseDuration,
+		RenewDeadline: DefaultRenewDeadline,
+		RetryPeriod:   DefaultRetryPeriod,
+	}
+}
+
+// DefaultIdentity returns $POD_NAME, falling back to the hostname.
+func DefaultIdentity() (string, error) {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name, nil
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("determine leader election identity: %w", err)
+	}
+	return host, nil
+}
+
+// IsLeader reports whether this replica currently holds the Lease.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns for the Lease until ctx is canceled and calls lead whenever
+// leadership is acquired. lead's context is canceled when leadership is lost;
+// Run waits for lead to return before campaigning again. When lead returns,
+// the Lease is released; a non-context error from lead stops Run.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context) error) error {
+	if e.Identity == "" {
+		return errors.New("leader election identity is required")
+	}
+	for {
+		var leadErr error
+		done := make(chan struct{})
+		runCtx, cancelRun := context.WithCancel(ctx)
+		lock := &trackingLock{Interface: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: e.Namespace, Name: e.Name},
+			Client:     e.Client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: e.Identity},
+		}}
+		le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   e.LeaseDuration,
+			RenewDeadline:   e.RenewDeadline,
+			RetryPeriod:     e.RetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            e.Name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					defer close(done)
+					defer cancelRun() // lead returned on its own: release the Lease
+					e.leading.Store(true)
+					slog.Info("acquired lease", "namespace", e.Namespace, "lease", e.Name, "identity", e.Identity)
+					leadErr = lead(ctx)
+				},
+				OnStoppedLeading: func() {
+					if e.leading.Swap(false) {
+						slog.Info("lost lease", "namespace", e.Namespace, "lease", e.Name)
+					}
+				},
+				OnNewLeader: func(identity string) {
+					if identity != e.Identity {
+						slog.Info("following leader", "leader", identity)
+					}
+				},
+			},
+		})
+		if err != nil {
+			cancelRun()
+			return fmt.Errorf("configure leader election: %w", err)
+		}
+
+		le.Run(runCtx)
+		cancelRun()
+		e.leading.Store(false)
+		if lock.written.Load() {
+			<-done // lead was started; wait until it observed the cancellation
+		}
+
+		if leadErr != nil && !errors.Is(leadErr, context.Canceled) {
+			return leadErr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// trackingLock records whether the Lease was ever written by this replica.
+// The elector starts OnStartedLeading in a goroutine, so a successful write is
+// the only synchronous signal that lead is going to run.
+type trackingLock struct {
+	resourcelock.Interface
+	written atomic.Bool
+}
+
+func (l *trackingLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Create(ctx, ler)
+	if err == nil {
+		l.written.Store(true)
+	}
+	return err
+}
+
+func (l *trackingLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	err := l.Interface.Update(ctx, ler)
+	if err == nil {
+		l.written.Store(true)
+	}
+	return err
+}
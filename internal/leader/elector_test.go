@@ -0,0 +1,115 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testElector(client kubernetes.Interface, identity string) *Elector {
+	e := NewElector(client, "flux-system", "fluxbrain", identity)
+	e.LeaseDuration = time.Second
+	e.RenewDeadline = 500 * time.Millisecond
+	e.RetryPeriod = 100 * time.Millisecond
+	return e
+}
+
+// replica runs an Elector whose lead function reports when it starts and blocks until canceled.
+type replica struct {
+	elector *Elector
+	started chan struct{}
+	cancel  context.CancelFunc
+	done    chan error
+}
+
+func startReplica(client kubernetes.Interface, identity string) *replica {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &replica{
+		elector: testElector(client, identity),
+		started: make(chan struct{}, 10),
+		cancel:  cancel,
+		done:    make(chan error, 1),
+	}
+	go func() {
+		r.done <- r.elector.Run(ctx, func(ctx context.Context) error {
+			r.started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+	return r
+}
+
+func waitStarted(t *testing.T, r *replica) {
+	t.Helper()
+	select {
+	case <-r.started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s never became leader", r.elector.Identity)
+	}
+}
+
+func holder(t *testing.T, client kubernetes.Interface) string {
+	t.Helper()
+	lease, err := client.CoordinationV1().Leases("flux-system").Get(context.Background(), "fluxbrain", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func TestElectorSingleLeaderAndFailover(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	a := startReplica(client, "replica-a")
+	waitStarted(t, a)
+	if !a.elector.IsLeader() {
+		t.Fatal("replica-a should lead")
+	}
+	if got := holder(t, client); got != "replica-a" {
+		t.Fatalf("lease holder = %q, want replica-a", got)
+	}
+
+	b := startReplica(client, "replica-b")
+	select {
+	case <-b.started:
+		t.Fatal("replica-b led while replica-a holds the lease")
+	case <-time.After(1500 * time.Millisecond):
+	}
+	if b.elector.IsLeader() {
+		t.Fatal("replica-b must follow")
+	}
+
+	// releasing the lease on shutdown lets the follower take over quickly
+	a.cancel()
+	if err := <-a.done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v, want context.Canceled", err)
+	}
+	waitStarted(t, b)
+	if got := holder(t, client); got != "replica-b" {
+		t.Fatalf("lease holder = %q, want replica-b", got)
+	}
+
+	b.cancel()
+	<-b.done
+}
+
+func TestElectorReturnsLeadError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	boom := errors.New("boom")
+
+	err := testElector(client, "replica-a").Run(context.Background(), func(context.Context) error {
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Run() = %v, want %v", err, boom)
+	}
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"":        slog.LevelInfo,
+		" INFO ":  slog.LevelInfo,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	}
+	for in, want := range cases {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("unknown level must be rejected")
+	}
+}
+
+func TestNewHonorsFormatAndLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept", "fingerprint", "abc")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("want exactly one JSON line, got %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "kept" || line["fingerprint"] != "abc" {
+		t.Errorf("unexpected line: %v", line)
+	}
+
+	buf.Reset()
+	logger, err = New(&buf, FormatText, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("details", "name", "apps")
+	if !strings.Contains(buf.String(), "msg=details name=apps") {
+		t.Errorf("unexpected text line: %q", buf.String())
+	}
+
+	if _, err := New(&buf, "xml", "info"); err == nil {
+		t.Error("unknown format must be rejected")
+	}
+}
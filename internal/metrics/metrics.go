@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fluxbrain"
+
+// Registry holds all fluxbrain metrics plus Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// CollectorRuns counts CollectErrors calls per collector and result (success/error).
+	CollectorRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_runs_total",
+		Help:      "Collector invocations by collector and result.",
+	}, []string{"collector", "result"})
+
+	// CollectorDuration observes how long each collector took.
+	CollectorDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "collector_duration_seconds",
+		Help:      "Duration of CollectErrors calls by collector.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"collector"})
+
+	// ErrorsCollected counts ErrorContexts returned per collector.
+	ErrorsCollected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_collected_total",
+		Help:      "ErrorContexts returned by collectors.",
+	}, []string{"collector"})
+
+	// ErrorsSkipped counts ErrorContexts that were not analyzed, by reason (silenced, backoff, notified, flapping, ready, duplicate).
+	ErrorsSkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_skipped_total",
+		Help:      "ErrorContexts skipped before analysis by reason.",
+	}, []string{"reason"})
+
+	// Analyses counts Analyzer calls by result (success/error).
+	Analyses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "analyses_total",
+		Help:      "Analyzer calls by result.",
+	}, []string{"result"})
+
+	// AnalysisDuration observes Analyzer call durations.
+	AnalysisDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "analysis_duration_seconds",
+		Help:      "Duration of Analyzer calls.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// Notifications counts Notify/Resolve calls by channel, kind (failure/reminder/escalation/resolved) and result.
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Notifier calls by channel, kind and result.",
+	}, []string{"channel", "kind", "result"})
+
+	// NotificationDuration observes notifier call durations by channel.
+	NotificationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "notification_duration_seconds",
+		Help:      "Duration of notifier calls by channel.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"channel"})
+
+	// CycleDuration observes RunOnce durations.
+	CycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cycle_duration_seconds",
+		Help:      "Duration of reconciliation cycles.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300},
+	})
+
+	// LastSuccessfulCycle is the unix time of the last cycle in which every collector succeeded.
+	LastSuccessfulCycle = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_cycle_timestamp_seconds",
+		Help:      "Unix time of the last cycle without collector errors.",
+	})
+
+	// OpenFailures is the number of failures the engine currently tracks as open.
+	OpenFailures = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "open_failures",
+		Help:      "Failures reported and not yet resolved.",
+	})
+
+	// StateFingerprints is the number of fingerprints the state store holds
+	// back, i.e. in backoff or notified (state.Counter).
+	StateFingerprints = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "state_fingerprints",
+		Help:      "Fingerprints in backoff or with a notification record in the state store.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		CollectorRuns,
+		CollectorDuration,
+		ErrorsCollected,
+		ErrorsSkipped,
+		Analyses,
+		AnalysisDuration,
+		Notifications,
+		NotificationDuration,
+		CycleDuration,
+		LastSuccessfulCycle,
+		OpenFailures,
+		StateFingerprints,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Result maps an error to the "result" label value.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -5,41 +5,145 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
 
+const defaultGitHubAPI = "https://api.github.com"
+
 // GitHubNotifier opens issues for persistent failures.
 type GitHubNotifier struct {
 	Owner string
 	Repo  string
 	Token string
+	// BaseURL overrides the API endpoint (GitHub Enterprise); defaults to api.github.com.
+	BaseURL string
 }
 
 func (g GitHubNotifier) Channel() string { return "github" }
 
+// Notify opens an issue for a new failure. Reminders and escalations comment on
+// the issue that is still open instead, so a long-running failure keeps one issue.
 func (g GitHubNotifier) Notify(ctx context.Context, ec types.ErrorContext, result types.AnalysisResult) error {
 	if g.Owner == "" || g.Repo == "" || g.Token == "" {
 		return fmt.Errorf("github notifier is not fully configured")
 	}
 
-	title := fmt.Sprintf("Fluxbrain: %s/%s %s reconciliation failure", ec.Resource.Namespace, ec.Resource.Name, ec.Resource.Kind)
 	body := fmt.Sprintf("Cluster: %s\nReason: %s\nSummary: %s\nRecommendations:\n- %s\nRetrySafe: %t\nRevision: %s",
 		ec.Cluster, result.RootCause, result.Summary, join(result.Recommendations), result.RetrySafe, ec.Git.Revision)
+	if ec.Fingerprint != "" {
+		body += "\n" + fingerprintLine(ec.Fingerprint)
+	}
+
+	if ec.Notification != nil && ec.Notification.Reminder > 0 {
+		issues, err := g.openIssues(ctx, ec)
+		if err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			comment := map[string]string{"body": alertTitle(ec) + "\n\n" + body}
+			return g.do(ctx, http.MethodPost, g.repoURL(fmt.Sprintf("/issues/%d/comments", issues[0])), comment, nil)
+		}
+	}
 
 	payload := map[string]string{
-		"title": title,
+		"title": issueTitle(ec),
 		"body":  body,
 	}
+	return g.do(ctx, http.MethodPost, g.repoURL("/issues"), payload, nil)
+}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/issues", g.Owner, g.Repo)
-	bodyBytes, err := json.Marshal(payload)
+// Resolve comments on and closes the open issue that Notify created for ec's
+// fingerprint; issues of other fingerprints of the same resource stay open.
+func (g GitHubNotifier) Resolve(ctx context.Context, ec types.ErrorContext) error {
+	if g.Owner == "" || g.Repo == "" || g.Token == "" {
+		return fmt.Errorf("github notifier is not fully configured")
+	}
+
+	issues, err := g.openIssues(ctx, ec)
 	if err != nil {
 		return err
 	}
+	comment := fmt.Sprintf("Fluxbrain observed that %s %s/%s recovered on cluster %s.",
+		ec.Resource.Kind, ec.Resource.Namespace, ec.Resource.Name, ec.Cluster)
+	for _, number := range issues {
+		issuePath := fmt.Sprintf("/issues/%d", number)
+		if err := g.do(ctx, http.MethodPost, g.repoURL(issuePath+"/comments"), map[string]string{"body": comment}, nil); err != nil {
+			return err
+		}
+		if err := g.do(ctx, http.MethodPatch, g.repoURL(issuePath), map[string]string{"state": "closed", "state_reason": "completed"}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openIssues returns the numbers of open issues Notify created for ec. With a
+// fingerprint, only issues whose body carries it match.
+func (g GitHubNotifier) openIssues(ctx context.Context, ec types.ErrorContext) ([]int, error) {
+	title := issueTitle(ec)
+	query := fmt.Sprintf("repo:%s/%s is:issue is:open in:title %q", g.Owner, g.Repo, title)
+	marker := ""
+	if ec.Fingerprint != "" {
+		marker = fingerprintLine(ec.Fingerprint)
+		query = fmt.Sprintf("repo:%s/%s is:issue is:open in:body %q", g.Owner, g.Repo, ec.Fingerprint)
+	}
+	var found struct {
+		Items []struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+			Body   string `json:"body"`
+		} `json:"items"`
+	}
+	if err := g.do(ctx, http.MethodGet, g.baseURL()+"/search/issues?q="+url.QueryEscape(query), nil, &found); err != nil {
+		return nil, err
+	}
+	var numbers []int
+	for _, issue := range found.Items {
+		// search is fuzzy
+		if issue.Title == title && strings.Contains(issue.Body, marker) {
+			numbers = append(numbers, issue.Number)
+		}
+	}
+	return numbers, nil
+}
+
+// fingerprintLine marks the issue of a fingerprint in its body.
+func fingerprintLine(fp string) string {
+	return "Fingerprint: " + fp
+}
+
+func issueTitle(ec types.ErrorContext) string {
+	return fmt.Sprintf("Fluxbrain: %s/%s %s reconciliation failure", ec.Resource.Namespace, ec.Resource.Name, ec.Resource.Kind)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+func (g GitHubNotifier) baseURL() string {
+	if g.BaseURL != "" {
+		return g.BaseURL
+	}
+	return defaultGitHubAPI
+}
+
+func (g GitHubNotifier) repoURL(path string) string {
+	return fmt.Sprintf("%s/repos/%s/%s%s", g.baseURL(), g.Owner, g.Repo, path)
+}
+
+// do sends a GitHub API request with an optional JSON body and decodes the response into out.
+func (g GitHubNotifier) do(ctx context.Context, method, endpoint string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		bodyBytes, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
 	if err != nil {
 		return err
 	}
@@ -55,5 +159,8 @@ func (g GitHubNotifier) Notify(ctx context.Context, ec types.ErrorContext, resul
 	if resp.StatusCode >= 300 {
 		return fmt.Errorf("github api returned %d", resp.StatusCode)
 	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
 	return nil
 }
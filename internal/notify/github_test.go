@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+type githubIssue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// fakeGitHub answers issue searches with issues and records every other request.
+type fakeGitHub struct {
+	issues []githubIssue
+
+	mu       sync.Mutex
+	query    string
+	requests []string
+	bodies   []map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodGet && r.URL.Path == "/search/issues" {
+		f.query = r.URL.Query().Get("q")
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": f.issues})
+		return
+	}
+	var body map[string]string
+	json.NewDecoder(r.Body).Decode(&body)
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.bodies = append(f.bodies, body)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newTestGitHub(t *testing.T, issues ...githubIssue) (*fakeGitHub, GitHubNotifier) {
+	t.Helper()
+	fake := &fakeGitHub{issues: issues}
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+	return fake, GitHubNotifier{Owner: "acme", Repo: "infra", Token: "secret", BaseURL: ts.URL}
+}
+
+func githubFailure(fp string) types.ErrorContext {
+	return types.ErrorContext{
+		Cluster:     "prod",
+		Resource:    types.ResourceRef{Kind: types.FluxResourceKindKustomization, Namespace: "flux-system", Name: "apps"},
+		Reason:      "ReconciliationFailed",
+		Git:         types.GitContext{Revision: "main@sha1:abc"},
+		Fingerprint: fp,
+	}
+}
+
+func TestGitHubNotifierOpensIssueWithFingerprint(t *testing.T) {
+	fake, g := newTestGitHub(t)
+	if err := g.Notify(context.Background(), githubFailure("aaa"), types.AnalysisResult{Summary: "bad manifest"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.requests) != 1 || fake.requests[0] != "POST /repos/acme/infra/issues" {
+		t.Fatalf("requests = %v, want one new issue", fake.requests)
+	}
+	body := fake.bodies[0]
+	if body["title"] != issueTitle(githubFailure("aaa")) || !strings.Contains(body["body"], "\nFingerprint: aaa") {
+		t.Errorf("issue = %+v, want the title and fingerprint line", body)
+	}
+}
+
+func TestGitHubNotifierCommentsRemindersOnTheOpenIssue(t *testing.T) {
+	ec := githubFailure("aaa")
+	title := issueTitle(ec)
+	fake, g := newTestGitHub(t,
+		githubIssue{Number: 1, Title: title, Body: "Fingerprint: bbb"},
+		githubIssue{Number: 2, Title: title, Body: "Fingerprint: aaa"},
+	)
+	ec.Notification = &types.NotificationInfo{Kind: "reminder", Reminder: 1}
+	if err := g.Notify(context.Background(), ec, types.AnalysisResult{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.requests) != 1 || fake.requests[0] != "POST /repos/acme/infra/issues/2/comments" {
+		t.Fatalf("requests = %v, want a comment on issue 2", fake.requests)
+	}
+	if !strings.HasPrefix(fake.bodies[0]["body"], "Fluxbrain Reminder 1") {
+		t.Errorf("comment = %q, want a reminder", fake.bodies[0]["body"])
+	}
+
+	// without an open issue a reminder opens a new one
+	fake.issues = nil
+	if err := g.Notify(context.Background(), ec, types.AnalysisResult{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.requests) != 2 || fake.requests[1] != "POST /repos/acme/infra/issues" {
+		t.Fatalf("requests = %v, want a new issue", fake.requests)
+	}
+}
+
+func TestGitHubNotifierResolveClosesOnlyTheFingerprintsIssue(t *testing.T) {
+	ec := githubFailure("aaa")
+	title := issueTitle(ec)
+	fake, g := newTestGitHub(t,
+		githubIssue{Number: 1, Title: title, Body: "Revision: main@sha1:abc\nFingerprint: bbb"},
+		githubIssue{Number: 2, Title: title, Body: "Revision: main@sha1:abc\nFingerprint: aaa"},
+		githubIssue{Number: 3, Title: "Fluxbrain: other", Body: "Fingerprint: aaa"},
+	)
+	if err := g.Resolve(context.Background(), ec); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fake.query, `in:body "aaa"`) {
+		t.Errorf("query = %q, want a search for the fingerprint", fake.query)
+	}
+	want := []string{"POST /repos/acme/infra/issues/2/comments", "PATCH /repos/acme/infra/issues/2"}
+	if strings.Join(fake.requests, ",") != strings.Join(want, ",") {
+		t.Fatalf("requests = %v, want %v", fake.requests, want)
+	}
+	if fake.bodies[1]["state"] != "closed" {
+		t.Errorf("patch = %+v, want the issue closed", fake.bodies[1])
+	}
+}
+
+func TestGitHubNotifierReportsAPIErrors(t *testing.T) {
+	_, g := newTestGitHub(t)
+	g.Token = "wrong"
+	if err := g.Resolve(context.Background(), githubFailure("aaa")); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Resolve() = %v, want the status code", err)
+	}
+}
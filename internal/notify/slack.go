@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
@@ -24,12 +25,32 @@ func (s SlackNotifier) Notify(ctx context.Context, ec types.ErrorContext, result
 		return fmt.Errorf("slack webhook is empty")
 	}
 
-	text := fmt.Sprintf("*Fluxbrain Alert*\n*Cluster:* %s\n*Resource:* %s/%s (%s)\n*Reason:* %s\n*Summary:* %s\n*Recommendation:* %s\n*Retry safe:* %t\n*Revision:* %s",
-		ec.Cluster, ec.Resource.Namespace, ec.Resource.Name, ec.Resource.Kind, result.RootCause, result.Summary, join(result.Recommendations), result.RetrySafe, ec.Git.Revision)
+	text := fmt.Sprintf("*%s*\n*Cluster:* %s\n*Resource:* %s/%s (%s)\n*Reason:* %s\n*Summary:* %s\n*Recommendation:* %s\n*Retry safe:* %t\n*Revision:* %s",
+		alertTitle(ec), ec.Cluster, ec.Resource.Namespace, ec.Resource.Name, ec.Resource.Kind, result.RootCause, result.Summary, join(result.Recommendations), result.RetrySafe, ec.Git.Revision)
 
-	payload := map[string]interface{}{
+	return s.post(ctx, map[string]interface{}{
 		"text": text,
+	})
+}
+
+// Resolve posts a green recovery message to Slack.
+func (s SlackNotifier) Resolve(ctx context.Context, ec types.ErrorContext) error {
+	if s.WebhookURL == "" {
+		return fmt.Errorf("slack webhook is empty")
 	}
+
+	text := fmt.Sprintf("*Fluxbrain Resolved*\n*Cluster:* %s\n*Resource:* %s/%s (%s)\n*Last reason:* %s\n*Revision:* %s",
+		ec.Cluster, ec.Resource.Namespace, ec.Resource.Name, ec.Resource.Kind, ec.Reason, ec.Git.Revision)
+
+	return s.post(ctx, map[string]interface{}{
+		"text": fmt.Sprintf("Resolved: %s/%s (%s)", ec.Resource.Namespace, ec.Resource.Name, ec.Resource.Kind),
+		"attachments": []map[string]string{
+			{"color": "good", "text": text},
+		},
+	})
+}
+
+func (s SlackNotifier) post(ctx context.Context, payload map[string]interface{}) error {
 	if s.ChannelID != "" {
 		payload["channel"] = s.ChannelID
 	}
@@ -65,3 +86,23 @@ func join(list []string) string {
 	}
 	return list[0]
 }
+
+// alertTitle marks flapping resources and reminders and escalations of a
+// failure that is still open.
+func alertTitle(ec types.ErrorContext) string {
+	n := ec.Notification
+	if n != nil && n.Kind == "flapping" && ec.Transitions != nil {
+		return fmt.Sprintf("Fluxbrain Flapping (%d transitions since %s)", ec.Transitions.Count, ec.Transitions.Since.UTC().Format(time.RFC3339))
+	}
+	if n == nil || n.Reminder == 0 {
+		return "Fluxbrain Alert"
+	}
+	since := ""
+	if !n.FirstNotified.IsZero() {
+		since = fmt.Sprintf(", failing since %s", n.FirstNotified.UTC().Format(time.RFC3339))
+	}
+	if n.Kind == "escalation" {
+		return fmt.Sprintf("Fluxbrain Escalation (reminder %d%s)", n.Reminder, since)
+	}
+	return fmt.Sprintf("Fluxbrain Reminder %d%s", n.Reminder, since)
+}
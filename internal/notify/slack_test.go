@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestSlackNotifierResolve(t *testing.T) {
+	var got struct {
+		Text        string              `json:"text"`
+		Channel     string              `json:"channel"`
+		Attachments []map[string]string `json:"attachments"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer ts.Close()
+
+	s := SlackNotifier{WebhookURL: ts.URL, ChannelID: "C123"}
+	if err := s.Resolve(context.Background(), githubFailure("aaa")); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Resolved: flux-system/apps (Kustomization)" || got.Channel != "C123" {
+		t.Errorf("message = %+v", got)
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0]["color"] != "good" || !strings.Contains(got.Attachments[0]["text"], "*Last reason:* ReconciliationFailed") {
+		t.Errorf("attachments = %+v, want one green recovery attachment", got.Attachments)
+	}
+}
+
+func TestSlackNotifierReportsWebhookErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	s := SlackNotifier{WebhookURL: ts.URL}
+	if err := s.Resolve(context.Background(), githubFailure("aaa")); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Resolve() = %v, want the status code", err)
+	}
+	if err := (SlackNotifier{}).Notify(context.Background(), types.ErrorContext{}, types.AnalysisResult{}); err == nil {
+		t.Error("an empty webhook must be rejected")
+	}
+}
@@ -22,11 +22,25 @@ func (w WebhookNotifier) Notify(ctx context.Context, ec types.ErrorContext, resu
 		return fmt.Errorf("webhook url is empty")
 	}
 
-	payload := map[string]interface{}{
+	return w.post(ctx, map[string]interface{}{
+		"status":  "failing",
 		"context": ec,
 		"result":  result,
+	})
+}
+
+// Resolve sends the last known context with status "resolved".
+func (w WebhookNotifier) Resolve(ctx context.Context, ec types.ErrorContext) error {
+	if w.URL == "" {
+		return fmt.Errorf("webhook url is empty")
 	}
+	return w.post(ctx, map[string]interface{}{
+		"status":  "resolved",
+		"context": ec,
+	})
+}
 
+func (w WebhookNotifier) post(ctx context.Context, payload map[string]interface{}) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return err
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+type webhookPayload struct {
+	Status  string                `json:"status"`
+	Context types.ErrorContext    `json:"context"`
+	Result  *types.AnalysisResult `json:"result"`
+}
+
+func TestWebhookNotifierSendsStatus(t *testing.T) {
+	var got []webhookPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		got = append(got, payload)
+	}))
+	defer ts.Close()
+
+	w := WebhookNotifier{URL: ts.URL}
+	ec := githubFailure("aaa")
+	ctx := context.Background()
+	if err := w.Notify(ctx, ec, types.AnalysisResult{Summary: "bad manifest"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Resolve(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Status != "failing" || got[0].Result == nil || got[0].Result.Summary != "bad manifest" {
+		t.Fatalf("failure payload = %+v", got)
+	}
+	if got[1].Status != "resolved" || got[1].Result != nil || got[1].Context.Fingerprint != "aaa" || got[1].Context.Resource != ec.Resource {
+		t.Errorf("resolve payload = %+v, want status resolved with the context", got[1])
+	}
+}
@@ -2,8 +2,13 @@ package reconcile
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/afeldman/fluxbrain/internal/metrics"
 	"github.com/afeldman/fluxbrain/internal/state"
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
@@ -18,12 +23,65 @@ type ErrorCollector interface {
 	CollectErrors(ctx context.Context) ([]types.ErrorContext, error)
 }
 
+// CycleCache holds data shared by the collectors of one cycle, e.g. an event
+// list. The engine resets it before every cycle.
+type CycleCache interface {
+	Reset()
+}
+
+// ReadinessChecker reports whether a Flux resource currently has Ready=True.
+type ReadinessChecker interface {
+	Ready(ctx context.Context, ref types.ResourceRef) (bool, error)
+}
+
+// Engine defaults for parallelism and deadlines.
+const (
+	DefaultConcurrency   = 4
+	DefaultItemTimeout   = 2 * time.Minute
+	DefaultNotifyTimeout = 30 * time.Second
+)
+
+// pushSource marks open failures that arrived via Process instead of a collector.
+const pushSource = ""
+
 // Engine orchestrates error collection, deduplication, backoff, analysis, and notification.
 type Engine struct {
 	Collectors []ErrorCollector
 	Analyzer   types.Analyzer
 	Notifiers  []types.Notifier
 	State      state.Store
+	// Readiness is optional. When set, contexts of Ready resources are dropped
+	// (stale events) and pushed failures resolve once their resource is Ready.
+	Readiness ReadinessChecker
+	// Concurrency bounds parallel collectors and ErrorContexts per cycle.
+	Concurrency int
+	// ItemTimeout bounds analysis plus notification of one ErrorContext (0 = none).
+	ItemTimeout time.Duration
+	// NotifyTimeout bounds each Notify/Resolve call (0 = none).
+	NotifyTimeout time.Duration
+	// Fingerprinter keys deduplication and backoff; nil uses state.Fingerprint.
+	Fingerprinter state.Fingerprinter
+	// Reminders decides when a failure that is still open is notified again.
+	Reminders state.ReminderPolicy
+	// Strictness selects which failures make RunOnce return a *RunError.
+	Strictness Strictness
+	// Flapping counts transitions between failing and recovered per resource
+	// within its window. A flapping resource gets one "flapping" notification
+	// (and reminders) instead of a notification per failure.
+	Flapping state.FlapPolicy
+	// Caches are reset before the collectors of a cycle run.
+	Caches []CycleCache
+	// ReportPath, when set, receives the JSON RunReport of every cycle.
+	ReportPath string
+	// Logger receives the engine's log lines; nil uses slog.Default.
+	Logger *slog.Logger
+
+	// mu guards open, the failures reported in earlier cycles. It mirrors the
+	// OpenFailure records of State and is reloaded from them every cycle;
+	// loaded reports whether the last load succeeded.
+	mu     sync.Mutex
+	open   map[string]*state.OpenFailure
+	loaded bool
 }
 
 // NewEngine creates a new reconciliation engine.
@@ -33,47 +91,775 @@ func NewEngine(collectors []ErrorCollector, analyzer types.Analyzer, notifiers [
 		Analyzer:   analyzer,
 		Notifiers:  notifiers,
 		State:      stateStore,
+
+		Concurrency:   DefaultConcurrency,
+		ItemTimeout:   DefaultItemTimeout,
+		NotifyTimeout: DefaultNotifyTimeout,
+		Reminders:     state.DefaultReminderPolicy,
+		Flapping:      state.DefaultFlapPolicy,
+		Strictness:    StrictnessCollectors,
+
+		open: make(map[string]*state.OpenFailure),
 	}
 }
 
 // RunOnce executes a single reconciliation cycle:
-// 1. Collect errors from all collectors
+// 1. Collect errors from all collectors (in parallel)
 // 2. Deduplicate via fingerprinting
-// 3. Check backoff state
-// 4. Analyze new/eligible errors
+// 3. Record history and transitions, check silences, backoff and whether a notification is due
+// 4. Analyze new/eligible errors (bounded worker pool)
 // 5. Notify downstream systems
-// 6. Update backoff state
+// 6. Update backoff and notification state
+// 7. Resolve failures that were open in the previous cycle but are gone now
+//
+// A failure whose resource still fails with another fingerprint (e.g. a new
+// revision) or, with a ReadinessChecker, is not Ready yet stays open. Open
+// failures are kept in the state store, so a once-mode run, a restarted
+// process or a new leader resolves failures an earlier one reported.
+//
+// Results are logged in collector order once the cycle finished, regardless
+// of which worker completed first. Failures are aggregated into a *RunError,
+// which is returned when Strictness considers them fatal.
 func (e *Engine) RunOnce(ctx context.Context) error {
-	for _, collector := range e.Collectors {
-		errorContexts, err := collector.CollectErrors(ctx)
-		if err != nil {
-			log.Printf("collector error: %v", err)
+	_, err := e.RunWithReport(ctx)
+	return err
+}
+
+// RunWithReport runs a cycle like RunOnce and returns its RunReport, which is
+// also logged and, with ReportPath set, written to a file.
+func (e *Engine) RunWithReport(ctx context.Context) (*RunReport, error) {
+	start := time.Now()
+	defer func() { metrics.CycleDuration.Observe(time.Since(start).Seconds()) }()
+
+	for _, c := range e.Caches {
+		c.Reset()
+	}
+	known := e.loadOpen(ctx)
+	runs := make([]collectorRun, len(e.Collectors))
+	e.parallel(len(e.Collectors), func(i int) {
+		runs[i] = e.collect(ctx, e.Collectors[i])
+	})
+
+	var items []workItem
+	failed := make(map[string]bool)
+	dedup := make(map[string]bool)
+	onsets := make(map[string]bool)
+	duplicates := 0
+	for _, run := range runs {
+		if run.err != nil {
+			e.logger().Error("collector failed", "collector", run.name, "error", run.err)
+			failed[run.name] = true
 			continue
 		}
+		for _, ec := range run.ecs {
+			fp := e.fingerprint(ec)
+			if dedup[fp] {
+				metrics.ErrorsSkipped.WithLabelValues("duplicate").Inc()
+				duplicates++
+				continue
+			}
+			dedup[fp] = true
+			item := workItem{fp: fp, ec: ec, source: run.name, resource: state.ResourceKey(ec.Cluster, ec.Resource)}
+			// a fingerprint that is not open is a new occurrence and the first
+			// failure of a resource that was not failing is a transition;
+			// without the stored open failures neither is known
+			if known {
+				item.occurrence = !e.failureOpen(fp)
+				if !onsets[item.resource] && !e.resourceOpen(item.resource) {
+					onsets[item.resource] = true
+					item.onset = true
+				}
+			}
+			items = append(items, item)
+		}
+	}
 
-		for _, ec := range errorContexts {
-			fp := state.Fingerprint(ec)
+	results := make([]itemResult, len(items))
+	e.parallel(len(items), func(i int) {
+		item := items[i]
+		if e.isReady(ctx, item.ec.Resource) {
+			metrics.ErrorsSkipped.WithLabelValues("ready").Inc()
+			results[i] = itemResult{item: item, ready: true}
+			return
+		}
+		results[i] = e.process(ctx, item)
+	})
 
-			if e.State.InBackoff(fp) {
-				log.Printf("skipping %s/%s (in backoff)", ec.Resource.Namespace, ec.Resource.Name)
-				continue
+	seen := make(map[string]bool)
+	failing := make(map[string]bool)
+	for i := range results {
+		r := &results[i]
+		if !r.ready {
+			seen[r.item.fp] = true
+			failing[r.item.resource] = true
+			if err := e.track(ctx, r.item.fp, r.item.ec, r.item.source, r.notified); err != nil {
+				r.stateErrs = append(r.stateErrs, err)
+			}
+			if r.flapNotified {
+				if err := e.track(ctx, r.item.resource, r.item.ec, r.item.source, true); err != nil {
+					r.stateErrs = append(r.stateErrs, err)
+				}
 			}
+		}
+		r.log(e.logger())
+	}
 
-			result, err := e.Analyzer.Analyze(ctx, ec)
-			if err != nil {
-				log.Printf("analysis failed for %s/%s: %v", ec.Resource.Namespace, ec.Resource.Name, err)
-				e.State.RegisterFailure(fp)
-				continue
+	resolutions := e.resolveGone(ctx, seen, failing, failed)
+	e.observeState(ctx)
+	if len(failed) == 0 {
+		metrics.LastSuccessfulCycle.SetToCurrentTime()
+	}
+
+	runErr := newRunError(runs, results, resolutions)
+	report := newRunReport(start, runs, duplicates, results, resolutions)
+	var err error
+	if e.Strictness.fatal(runErr) {
+		err = runErr
+		report.Error = runErr.Error()
+	}
+	e.publish(report)
+	return report, err
+}
+
+// collectorRun is the outcome of one collector in a cycle.
+type collectorRun struct {
+	name     string
+	ecs      []types.ErrorContext
+	err      error
+	duration time.Duration
+}
+
+// collect runs one collector and records its duration, result and yield.
+func (e *Engine) collect(ctx context.Context, c ErrorCollector) collectorRun {
+	run := collectorRun{name: collectorName(c)}
+	start := time.Now()
+	run.ecs, run.err = c.CollectErrors(ctx)
+	run.duration = time.Since(start)
+	metrics.CollectorDuration.WithLabelValues(run.name).Observe(run.duration.Seconds())
+	metrics.CollectorRuns.WithLabelValues(run.name, metrics.Result(run.err)).Inc()
+	metrics.ErrorsCollected.WithLabelValues(run.name).Add(float64(len(run.ecs)))
+	return run
+}
+
+func (e *Engine) fingerprint(ec types.ErrorContext) string {
+	if e.Fingerprinter == nil {
+		return state.Fingerprint(ec)
+	}
+	return e.Fingerprinter.Fingerprint(ec)
+}
+
+// observeState updates the open failure and state store gauges.
+func (e *Engine) observeState(ctx context.Context) {
+	e.mu.Lock()
+	open := len(e.open)
+	e.mu.Unlock()
+	metrics.OpenFailures.Set(float64(open))
+	if c, ok := e.State.(state.Counter); ok {
+		if n, err := c.Len(ctx); err == nil {
+			metrics.StateFingerprints.Set(float64(n))
+		}
+	}
+}
+
+// Process runs backoff check, analysis, notification and state update for a
+// single ErrorContext. It is used by push-based collectors.
+func (e *Engine) Process(ctx context.Context, ec types.ErrorContext) {
+	item := workItem{fp: e.fingerprint(ec), ec: ec, source: pushSource, resource: state.ResourceKey(ec.Cluster, ec.Resource)}
+	if e.openLoaded() || e.loadOpen(ctx) {
+		item.occurrence = !e.failureOpen(item.fp)
+		item.onset = !e.resourceOpen(item.resource)
+	}
+	r := e.process(ctx, item)
+	if err := e.track(ctx, item.fp, ec, pushSource, r.notified); err != nil {
+		r.stateErrs = append(r.stateErrs, err)
+	}
+	if r.flapNotified {
+		if err := e.track(ctx, item.resource, ec, pushSource, true); err != nil {
+			r.stateErrs = append(r.stateErrs, err)
+		}
+	}
+	r.log(e.logger())
+	e.observeState(ctx)
+}
+
+// workItem is one deduplicated ErrorContext of a cycle.
+type workItem struct {
+	fp string
+	ec types.ErrorContext
+	// source names the collector that reported ec, or is pushSource.
+	source string
+	// resource is the state.ResourceKey of ec; onset marks the first failure
+	// of a resource that was not failing before.
+	resource string
+	onset    bool
+	// occurrence marks a fingerprint that was not open before, i.e. never
+	// seen or resolved (forgotten) since it was last seen.
+	occurrence bool
+}
+
+// itemResult records what happened to a workItem so it can be logged in order.
+type itemResult struct {
+	item       workItem
+	ready      bool
+	silenced   *state.Silence
+	inBackoff  bool
+	suppressed bool
+	flapping   bool
+	analyzed   bool
+	analysis   time.Duration
+	analyzeErr error
+	deliveries []delivery
+	stateErrs  []error
+	notified   bool
+	// flapNotified marks a flapping resource whose notification was sent.
+	flapNotified bool
+}
+
+// skipReason returns why the result was not analyzed, or "".
+func (r itemResult) skipReason() string {
+	switch {
+	case r.ready:
+		return "ready"
+	case r.silenced != nil:
+		return "silenced"
+	case r.inBackoff:
+		return "backoff"
+	case r.suppressed && r.flapping:
+		return "flapping"
+	case r.suppressed:
+		return "notified"
+	}
+	return ""
+}
+
+// delivery is one Notify or Resolve call.
+type delivery struct {
+	channel  string
+	kind     string
+	duration time.Duration
+	err      error
+}
+
+// log writes the outcome of r, with the failure's attributes on every line.
+func (r itemResult) log(logger *slog.Logger) {
+	logger = logger.With(failureAttrs(r.item.fp, r.item.ec)...)
+	for _, err := range r.stateErrs {
+		logger.Warn("state store error", "error", err)
+	}
+	switch {
+	case r.ready:
+		logger.Info("skipping failure, resource is Ready")
+	case r.silenced != nil:
+		logger.Info("skipping failure, silenced", "silence", r.silenced.Mode)
+	case r.inBackoff:
+		logger.Info("skipping failure, in backoff")
+	case r.suppressed && r.flapping:
+		logger.Info("skipping failure, resource is flapping and already notified")
+	case r.suppressed:
+		logger.Info("skipping failure, already notified and no reminder due")
+	case r.analyzeErr != nil:
+		logger.Warn("analysis failed", "error", r.analyzeErr)
+	}
+	for _, d := range r.deliveries {
+		logDelivery(logger, d)
+	}
+}
+
+// logDelivery logs one Notify or Resolve call.
+func logDelivery(logger *slog.Logger, d delivery) {
+	if d.err != nil {
+		logger.Warn("notification failed", "channel", d.channel, "notification", d.kind, "duration", d.duration, "error", d.err)
+		return
+	}
+	logger.Info("notification sent", "channel", d.channel, "notification", d.kind, "duration", d.duration)
+}
+
+// failureAttrs are the slog attributes identifying a failure.
+func failureAttrs(fp string, ec types.ErrorContext) []any {
+	return []any{
+		"fingerprint", fp,
+		"cluster", ec.Cluster,
+		"kind", string(ec.Resource.Kind),
+		"namespace", ec.Resource.Namespace,
+		"name", ec.Resource.Name,
+	}
+}
+
+// logger returns Logger or the default logger.
+func (e *Engine) logger() *slog.Logger {
+	if e.Logger != nil {
+		return e.Logger
+	}
+	return slog.Default()
+}
+
+// process handles one ErrorContext within ItemTimeout.
+func (e *Engine) process(ctx context.Context, item workItem) itemResult {
+	res := itemResult{item: item}
+	ec := item.ec
+	ec.Fingerprint = item.fp
+	if history, err := e.State.Observe(ctx, item.fp, time.Now(), ec.Git.Revision, item.occurrence); err != nil {
+		res.stateErrs = append(res.stateErrs, err)
+	} else {
+		ec.History = &history
+	}
+	if stats, err := e.transitions(ctx, item); err != nil {
+		res.stateErrs = append(res.stateErrs, err)
+	} else if stats != nil {
+		ec.Transitions = stats
+		res.flapping = e.Flapping.Flapping(stats.Count)
+	}
+
+	silence, err := e.State.SilenceOf(ctx, item.fp)
+	if err != nil {
+		res.stateErrs = append(res.stateErrs, err) // fail open
+	}
+	if silence.Active(time.Now(), item.ec.Git.Revision) {
+		metrics.ErrorsSkipped.WithLabelValues("silenced").Inc()
+		res.silenced = silence
+		return res
+	}
+
+	inBackoff, err := e.State.InBackoff(ctx, item.fp)
+	if err != nil {
+		res.stateErrs = append(res.stateErrs, err) // fail open
+	}
+	if inBackoff {
+		metrics.ErrorsSkipped.WithLabelValues("backoff").Inc()
+		res.inBackoff = true
+		return res
+	}
+
+	// a flapping resource is notified once under its resource key; its
+	// individual failures are neither notified nor resolved, the resource is
+	// resolved once it is stable again
+	notifyKey := item.fp
+	if res.flapping {
+		notifyKey = item.resource
+		ec.Fingerprint = item.resource
+	}
+	prev, err := e.State.NotifyState(ctx, notifyKey)
+	if err != nil {
+		res.stateErrs = append(res.stateErrs, err) // fail open: notify as new
+	}
+	now := time.Now()
+	decision := e.Reminders.Decide(prev, now)
+	if !decision.Notify {
+		reason := "notified"
+		if res.flapping {
+			reason = "flapping"
+		}
+		metrics.ErrorsSkipped.WithLabelValues(reason).Inc()
+		res.suppressed = true
+		res.notified = !res.flapping
+		res.flapNotified = res.flapping
+		return res
+	}
+	if res.flapping && decision.Kind == state.NotifyFirst {
+		decision.Kind = state.NotifyFlapping
+	}
+
+	ctx, cancel := withTimeout(ctx, e.ItemTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := e.Analyzer.Analyze(ctx, ec)
+	res.analyzed, res.analysis = true, time.Since(start)
+	metrics.AnalysisDuration.Observe(res.analysis.Seconds())
+	metrics.Analyses.WithLabelValues(metrics.Result(err)).Inc()
+	if err != nil {
+		res.analyzeErr = err
+		if err := e.State.RegisterFailure(ctx, item.fp, item.ec.Resource); err != nil {
+			res.stateErrs = append(res.stateErrs, err)
+		}
+		return res
+	}
+
+	next := prev.Next(decision, now)
+	ec.Notification = &types.NotificationInfo{
+		Kind:          decision.Kind,
+		Reminder:      decision.Reminder,
+		FirstNotified: next.FirstNotified,
+	}
+	accepted := 0
+	for _, notifier := range e.Notifiers {
+		d := e.notify(ctx, channelOf(notifier), decision.Kind, func(nctx context.Context) error {
+			return notifier.Notify(nctx, ec, result)
+		})
+		if d.err == nil {
+			accepted++
+		}
+		res.deliveries = append(res.deliveries, d)
+	}
+
+	// a failure no channel accepted is retried next cycle
+	if accepted > 0 {
+		if err := e.State.RecordNotification(ctx, notifyKey, next); err != nil {
+			res.stateErrs = append(res.stateErrs, err)
+		}
+		if rec, ok := e.State.(state.ResultRecorder); ok {
+			if err := rec.RecordResult(ctx, item.fp, result); err != nil {
+				res.stateErrs = append(res.stateErrs, err)
+			}
+		}
+	}
+	if err := e.State.RegisterSuccess(ctx, item.fp); err != nil {
+		res.stateErrs = append(res.stateErrs, err)
+	}
+	res.notified = !res.flapping
+	res.flapNotified = res.flapping && accepted > 0
+	return res
+}
+
+// transitions records the onset of item's failure and returns the resource's
+// transitions within the flapping window, or nil when the window is 0. Once a
+// resource fails again without flapping, its flapping notification record is
+// cleared so a later flapping episode is notified again.
+func (e *Engine) transitions(ctx context.Context, item workItem) (*types.TransitionStats, error) {
+	window := e.Flapping.Window
+	if window <= 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	var n int
+	var err error
+	if item.onset {
+		n, err = e.State.RecordTransition(ctx, item.resource, now, window)
+	} else {
+		n, err = e.State.Transitions(ctx, item.resource, now, window)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item.onset && !e.Flapping.Flapping(n) {
+		prev, err := e.State.NotifyState(ctx, item.resource)
+		if err == nil && prev.Notified() {
+			err = e.State.RecordNotification(ctx, item.resource, state.NotifyState{})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &types.TransitionStats{Count: n, Since: now.Add(-window)}, nil
+}
+
+// notify calls fn within NotifyTimeout and records it under channel and kind.
+func (e *Engine) notify(ctx context.Context, channel, kind string, fn func(ctx context.Context) error) delivery {
+	ctx, cancel := withTimeout(ctx, e.NotifyTimeout)
+	defer cancel()
+	start := time.Now()
+	d := delivery{channel: channel, kind: kind}
+	d.err = fn(ctx)
+	d.duration = time.Since(start)
+	metrics.NotificationDuration.WithLabelValues(channel).Observe(d.duration.Seconds())
+	metrics.Notifications.WithLabelValues(channel, kind, metrics.Result(d.err)).Inc()
+	return d
+}
+
+// parallel runs fn for 0..n-1 on at most Concurrency goroutines and waits for all.
+func (e *Engine) parallel(n int, fn func(i int)) {
+	workers := e.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
 			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
 
-			for _, notifier := range e.Notifiers {
-				if err := notifier.Notify(ctx, ec, result); err != nil {
-					log.Printf("notification failed: %v", err)
-				}
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// collectorName names a collector for metrics.
+func collectorName(c ErrorCollector) string {
+	if n, ok := c.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", c)
+}
+
+// channelOf names a notifier for logs and metrics.
+func channelOf(n types.Notifier) string {
+	if c, ok := n.(interface{ Channel() string }); ok {
+		return c.Channel()
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+// Failure describes a failure that was reported and has not resolved yet.
+type Failure struct {
+	Fingerprint string            `json:"fingerprint"`
+	Cluster     string            `json:"cluster"`
+	Resource    types.ResourceRef `json:"resource"`
+	Reason      string            `json:"reason"`
+	Revision    string            `json:"revision,omitempty"`
+	Notified    bool              `json:"notified"`
+}
+
+// OpenFailures returns the failures this engine tracks as open, ordered by fingerprint.
+func (e *Engine) OpenFailures() []Failure {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make([]Failure, 0, len(e.open))
+	for fp, o := range e.open {
+		out = append(out, newFailure(fp, *o))
+	}
+	sortFailures(out)
+	return out
+}
+
+// LoadFailures returns the open failures stored in store, ordered by
+// fingerprint. Unlike Engine.OpenFailures it needs no running engine and
+// sees the failures of every process sharing the store.
+func LoadFailures(ctx context.Context, store state.Store) ([]Failure, error) {
+	open, err := store.OpenFailures(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Failure, 0, len(open))
+	for fp, o := range open {
+		out = append(out, newFailure(fp, o))
+	}
+	sortFailures(out)
+	return out, nil
+}
+
+func newFailure(fp string, o state.OpenFailure) Failure {
+	return Failure{
+		Fingerprint: fp,
+		Cluster:     o.Context.Cluster,
+		Resource:    o.Context.Resource,
+		Reason:      o.Context.Reason,
+		Revision:    o.Context.Git.Revision,
+		Notified:    o.Notified,
+	}
+}
+
+func sortFailures(failures []Failure) {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Fingerprint < failures[j].Fingerprint })
+}
+
+// loadOpen replaces the open failures with the records of State and reports
+// whether it succeeded. When the store cannot be read, the failures this
+// process knows about are kept.
+func (e *Engine) loadOpen(ctx context.Context) bool {
+	stored, err := e.State.OpenFailures(ctx)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loaded = err == nil
+	if err != nil {
+		e.logger().Warn("loading open failures failed, using the ones of this process", "error", err)
+		return false
+	}
+	e.open = make(map[string]*state.OpenFailure, len(stored))
+	for fp, o := range stored {
+		e.open[fp] = &o
+	}
+	return true
+}
+
+// openLoaded reports whether the last load of the open failures succeeded.
+func (e *Engine) openLoaded() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.loaded
+}
+
+// track remembers fp as open until a later cycle no longer reports it and
+// stores the record when it changed.
+func (e *Engine) track(ctx context.Context, fp string, ec types.ErrorContext, source string, notified bool) error {
+	e.mu.Lock()
+	if e.open == nil {
+		e.open = make(map[string]*state.OpenFailure)
+	}
+	prev := e.open[fp]
+	o := state.NewOpenFailure(ec, source, notified || (prev != nil && prev.Notified))
+	e.open[fp] = &o
+	e.mu.Unlock()
+
+	if prev != nil && prev.Equal(o) {
+		return nil
+	}
+	return e.State.MarkOpen(ctx, fp, o)
+}
+
+// resolveGone resolves open failures that disappeared, ordered by fingerprint.
+// Failures of collectors that errored this cycle stay open, since their
+// absence proves nothing, and so do failures of a resource that is failing
+// with another fingerprint (e.g. a new revision) in this cycle.
+func (e *Engine) resolveGone(ctx context.Context, seen, failing, failed map[string]bool) []resolution {
+	type candidate struct {
+		ptr  *state.OpenFailure
+		snap state.OpenFailure
+	}
+	candidates := make(map[string]candidate)
+	e.mu.Lock()
+	for fp, o := range e.open {
+		if failed[o.Source] || seen[fp] || failing[state.ResourceKey(o.Context.Cluster, o.Context.Resource)] {
+			continue
+		}
+		candidates[fp] = candidate{ptr: o, snap: *o}
+	}
+	e.mu.Unlock()
+
+	var resolved []resolution
+	var flapped []string
+	for fp, c := range candidates {
+		if fp == state.ResourceKey(c.snap.Context.Cluster, c.snap.Context.Resource) {
+			flapped = append(flapped, fp)
+			continue
+		}
+		// pushed failures are never re-listed, so only readiness can resolve
+		// them; with a ReadinessChecker every failure waits for Ready=True
+		if (c.snap.Source == pushSource || e.Readiness != nil) && !e.isReady(ctx, c.snap.Context.Resource) {
+			continue
+		}
+
+		e.mu.Lock()
+		current := e.open[fp]
+		if current == c.ptr {
+			delete(e.open, fp)
+			c.snap = *current
+		}
+		e.mu.Unlock()
+
+		if current == c.ptr {
+			resolved = append(resolved, e.resolve(ctx, fp, c.snap))
+		}
+	}
+	// a flapping resource is resolved once none of its failures is open and
+	// it stopped flapping, so a resource that keeps flapping is not notified
+	// again on every failure
+	for _, key := range flapped {
+		c := candidates[key]
+		if e.resourceOpen(key) || e.flapping(ctx, key) {
+			continue
+		}
+		e.mu.Lock()
+		current := e.open[key]
+		if current == c.ptr {
+			delete(e.open, key)
+			c.snap = *current
+		}
+		e.mu.Unlock()
+
+		if current == c.ptr {
+			resolved = append(resolved, e.resolve(ctx, key, c.snap))
+		}
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].fp < resolved[j].fp })
+	return resolved
+}
+
+// resolution records what happened when an open failure was resolved.
+type resolution struct {
+	fp         string
+	ec         types.ErrorContext
+	deliveries []delivery
+	stateErrs  []error
+}
+
+// resolve tells every Resolver notifier that a notified failure recovered and clears its state.
+func (e *Engine) resolve(ctx context.Context, fp string, o state.OpenFailure) resolution {
+	logger := e.logger().With(failureAttrs(fp, o.Context)...)
+	logger.Info("failure resolved")
+	res := resolution{fp: fp, ec: o.Context}
+	ec := o.Context
+	ec.Fingerprint = fp
+	if o.Notified {
+		for _, notifier := range e.Notifiers {
+			resolver, ok := notifier.(types.Resolver)
+			if !ok {
+				continue
 			}
+			d := e.notify(ctx, channelOf(notifier), "resolved", func(rctx context.Context) error {
+				return resolver.Resolve(rctx, ec)
+			})
+			logDelivery(logger, d)
+			res.deliveries = append(res.deliveries, d)
+		}
+	}
+	if err := e.State.Forget(ctx, fp); err != nil {
+		res.stateErrs = append(res.stateErrs, err)
+	}
+	// the resource recovered once none of its failures is open; resolving
+	// its flapping notification is no transition
+	key := state.ResourceKey(o.Context.Cluster, o.Context.Resource)
+	if e.Flapping.Window > 0 && fp != key && !e.resourceOpen(key) {
+		if _, err := e.State.RecordTransition(ctx, key, time.Now(), e.Flapping.Window); err != nil {
+			res.stateErrs = append(res.stateErrs, err)
+		}
+	}
+	for _, err := range res.stateErrs {
+		logger.Warn("state store error", "error", err)
+	}
+	return res
+}
+
+// failureOpen reports whether fp is open.
+func (e *Engine) failureOpen(fp string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.open[fp]
+	return ok
+}
 
-			e.State.RegisterSuccess(fp)
+// resourceOpen reports whether a failure of the resource key is open. The
+// flapping notification tracked under the key itself is no failure.
+func (e *Engine) resourceOpen(key string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for fp, o := range e.open {
+		if fp != key && state.ResourceKey(o.Context.Cluster, o.Context.Resource) == key {
+			return true
 		}
 	}
-	return nil
+	return false
+}
+
+// flapping reports whether the resource key is still flapping. When its
+// transitions cannot be read it is assumed to be.
+func (e *Engine) flapping(ctx context.Context, key string) bool {
+	if e.Flapping.Window <= 0 {
+		return false
+	}
+	n, err := e.State.Transitions(ctx, key, time.Now(), e.Flapping.Window)
+	if err != nil {
+		e.logger().Warn("reading transitions failed", "resource", key, "error", err)
+		return true
+	}
+	return e.Flapping.Flapping(n)
+}
+
+func (e *Engine) isReady(ctx context.Context, ref types.ResourceRef) bool {
+	if e.Readiness == nil {
+		return false
+	}
+	ready, err := e.Readiness.Ready(ctx, ref)
+	if err != nil {
+		e.logger().Warn("readiness check failed", "kind", string(ref.Kind), "namespace", ref.Namespace, "name", ref.Name, "error", err)
+		return false
+	}
+	return ready
 }
@@ -0,0 +1,843 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/afeldman/fluxbrain/internal/metrics"
+	"github.com/afeldman/fluxbrain/internal/state"
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+type fakeCollector struct {
+	mu  sync.Mutex
+	ecs []types.ErrorContext
+	err error
+}
+
+func (f *fakeCollector) set(err error, ecs ...types.ErrorContext) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ecs, f.err = ecs, err
+}
+
+func (f *fakeCollector) CollectErrors(context.Context) ([]types.ErrorContext, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ecs, f.err
+}
+
+type fakeAnalyzer struct{ err error }
+
+func (f fakeAnalyzer) Analyze(_ context.Context, ec types.ErrorContext) (types.AnalysisResult, error) {
+	return types.AnalysisResult{Summary: ec.ErrorMsg}, f.err
+}
+
+type recordingNotifier struct {
+	mu       sync.Mutex
+	notified []string
+	resolved []string
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, ec types.ErrorContext, _ types.AnalysisResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notified = append(r.notified, ec.Resource.Name)
+	return nil
+}
+
+func (r *recordingNotifier) Resolve(_ context.Context, ec types.ErrorContext) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolved = append(r.resolved, ec.Resource.Name)
+	return nil
+}
+
+type readiness map[string]bool
+
+func (r readiness) Ready(_ context.Context, ref types.ResourceRef) (bool, error) {
+	return r[ref.Name], nil
+}
+
+func failure(name string) types.ErrorContext {
+	return types.ErrorContext{
+		Cluster:  "prod",
+		Resource: types.ResourceRef{Kind: types.FluxResourceKindKustomization, Namespace: "flux-system", Name: name},
+		Reason:   "ReconciliationFailed",
+		ErrorMsg: "apply failed",
+	}
+}
+
+func TestEngineResolvesDisappearedFailures(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"), failure("infra"))
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	ctx := context.Background()
+
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 2 || len(n.resolved) != 0 {
+		t.Fatalf("unexpected first cycle: notified=%v resolved=%v", n.notified, n.resolved)
+	}
+
+	// a failing collector proves nothing about its open failures
+	col.set(errors.New("api down"))
+	var runErr *RunError
+	if err := e.RunOnce(ctx); !errors.As(err, &runErr) || runErr.CollectorFailures != 1 {
+		t.Fatalf("a cycle without a working collector must fail, got %v", err)
+	}
+	if len(n.resolved) != 0 {
+		t.Fatalf("collector errors must not resolve failures: %v", n.resolved)
+	}
+
+	col.set(nil, failure("infra"))
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.resolved) != 1 || n.resolved[0] != "apps" {
+		t.Fatalf("expected apps to resolve, got %v", n.resolved)
+	}
+}
+
+func TestEngineResolvesFailuresOfAnEarlierProcess(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"))
+	store := state.NewMemoryStore(0, 0)
+	first := &recordingNotifier{}
+	ctx := context.Background()
+	mustDo(t, NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{first}, store).RunOnce(ctx))
+	fp := state.Fingerprint(failure("apps"))
+	mustDo(t, store.Silence(ctx, fp, state.Silence{Mode: state.SilenceResolved}))
+
+	// a once-mode run, a restart or a new leader shares only the store
+	col.set(nil)
+	second := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{second}, store)
+	mustDo(t, e.RunOnce(ctx))
+	if len(second.resolved) != 1 || second.resolved[0] != "apps" {
+		t.Fatalf("the failure of the earlier engine should resolve, got %v", second.resolved)
+	}
+	if open, _ := store.OpenFailures(ctx); len(open) != 0 {
+		t.Errorf("resolved failures must be forgotten, left %+v", open)
+	}
+	if s, _ := store.SilenceOf(ctx, fp); s != nil {
+		t.Errorf("the acknowledgement should end on resolution, got %+v", s)
+	}
+}
+
+func TestEngineKeepsOldRevisionsOpenWhileTheResourceFails(t *testing.T) {
+	apps := failure("apps")
+	apps.Git.Revision = "main@sha1:aaa"
+	col := &fakeCollector{}
+	col.set(nil, apps)
+	n := &recordingNotifier{}
+	ready := readiness{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	ctx := context.Background()
+	mustDo(t, e.RunOnce(ctx))
+
+	// a new commit that is still broken is a new failure, not a recovery
+	apps.Git.Revision = "main@sha1:bbb"
+	col.set(nil, apps)
+	mustDo(t, e.RunOnce(ctx))
+	if len(n.notified) != 2 || len(n.resolved) != 0 {
+		t.Fatalf("notified=%v resolved=%v, want two notifications and no resolve", n.notified, n.resolved)
+	}
+	if got := len(e.OpenFailures()); got != 2 {
+		t.Fatalf("both revisions should stay open, got %d", got)
+	}
+
+	// with a ReadinessChecker, disappearing is not enough
+	e.Readiness = ready
+	col.set(nil)
+	mustDo(t, e.RunOnce(ctx))
+	if len(n.resolved) != 0 {
+		t.Fatalf("a resource that is not Ready must not resolve: %v", n.resolved)
+	}
+
+	ready["apps"] = true
+	mustDo(t, e.RunOnce(ctx))
+	if len(n.resolved) != 2 || len(e.OpenFailures()) != 0 {
+		t.Fatalf("both revisions should resolve once Ready, resolved=%v", n.resolved)
+	}
+}
+
+func TestEngineReadinessResolvesStaleAndPushedFailures(t *testing.T) {
+	col := &fakeCollector{}
+	n := &recordingNotifier{}
+	ready := readiness{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	e.Readiness = ready
+	ctx := context.Background()
+
+	e.Process(ctx, failure("pushed"))
+	col.set(nil, failure("stale"))
+	ready["stale"] = true
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 1 || n.notified[0] != "pushed" || len(n.resolved) != 0 {
+		t.Fatalf("stale events of Ready resources must be skipped: notified=%v resolved=%v", n.notified, n.resolved)
+	}
+
+	ready["pushed"] = true
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.resolved) != 1 || n.resolved[0] != "pushed" {
+		t.Fatalf("pushed failure should resolve once Ready, got %v", n.resolved)
+	}
+}
+
+func TestEngineDoesNotResolveUnnotifiedFailures(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"))
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{err: errors.New("analyzer down")}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	ctx := context.Background()
+
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	col.set(nil)
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 0 || len(n.resolved) != 0 {
+		t.Fatalf("nothing was notified, so nothing should be resolved: %v %v", n.notified, n.resolved)
+	}
+	if in, _ := e.State.InBackoff(ctx, state.Fingerprint(failure("apps"))); in {
+		t.Error("resolution should clear the backoff state")
+	}
+}
+
+func TestEngineUsesFingerprinter(t *testing.T) {
+	apps, infra := failure("apps"), failure("infra")
+	apps.Git.Repository = "https://github.com/org/fleet"
+	infra.Git.Repository = apps.Git.Repository
+	col := &fakeCollector{}
+	col.set(nil, apps, infra)
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	e.Fingerprinter = state.NewFingerprinter(state.FingerprintOptions{GroupBySource: true})
+
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 1 {
+		t.Fatalf("failures of one source should be notified once, got %v", n.notified)
+	}
+}
+
+func TestEngineRemindsAndEscalates(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"))
+	n := &kindNotifier{}
+	store := state.NewMemoryStore(0, 0)
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, store)
+	e.Reminders = state.ReminderPolicy{Intervals: []time.Duration{time.Hour}, EscalateAfter: 1}
+	ctx := context.Background()
+	fp := state.Fingerprint(failure("apps"))
+
+	for i := 0; i < 2; i++ {
+		if err := e.RunOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(n.kinds) != 1 || n.kinds[0] != "failure" {
+		t.Fatalf("unchanged failure should be notified once, got %v", n.kinds)
+	}
+
+	// pretend the last notification is older than the reminder interval
+	for i := 0; i < 2; i++ {
+		ns, _ := store.NotifyState(ctx, fp)
+		ns.LastNotified = ns.LastNotified.Add(-2 * time.Hour)
+		mustDo(t, store.RecordNotification(ctx, fp, ns))
+		if err := e.RunOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"failure", "reminder 1", "escalation 2"}; !slices.Equal(n.kinds, want) {
+		t.Fatalf("notifications = %v, want %v", n.kinds, want)
+	}
+
+	col.set(nil)
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ns, _ := store.NotifyState(ctx, fp); ns.Notified() {
+		t.Error("resolution should forget the notification record")
+	}
+}
+
+func TestEngineHonorsSilences(t *testing.T) {
+	apps := failure("apps")
+	apps.Git.Revision = "main@sha1:aaa"
+	col := &fakeCollector{}
+	col.set(nil, apps)
+	n := &recordingNotifier{}
+	store := state.NewMemoryStore(0, 0)
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, store)
+	e.Fingerprinter = state.NewFingerprinter(state.FingerprintOptions{})
+	ctx := context.Background()
+	fp := e.fingerprint(apps)
+
+	mustDo(t, store.Silence(ctx, fp, state.Silence{Mode: state.SilenceRevision, Revision: "main@sha1:aaa"}))
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 0 {
+		t.Fatalf("acknowledged revision must not be notified: %v", n.notified)
+	}
+
+	apps.Git.Revision = "main@sha1:bbb"
+	col.set(nil, apps)
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 1 {
+		t.Fatalf("a new revision should end the acknowledgement: %v", n.notified)
+	}
+
+	snooze := state.Silence{Mode: state.SilenceSnooze, Until: time.Now().Add(time.Hour)}
+	mustDo(t, store.Silence(ctx, fp, snooze))
+	col.set(nil)
+	if err := e.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := store.SilenceOf(ctx, fp); s == nil {
+		t.Error("a running snooze should survive resolution")
+	}
+}
+
+// kindNotifier records the notification kind and reminder number, and the
+// transition count when the context carries one.
+type kindNotifier struct {
+	kinds       []string
+	transitions []int
+}
+
+func (k *kindNotifier) Notify(_ context.Context, ec types.ErrorContext, _ types.AnalysisResult) error {
+	kind := ec.Notification.Kind
+	if ec.Notification.Reminder > 0 {
+		kind += " " + strconv.Itoa(ec.Notification.Reminder)
+	}
+	k.kinds = append(k.kinds, kind)
+	if ec.Transitions != nil {
+		k.transitions = append(k.transitions, ec.Transitions.Count)
+	}
+	return nil
+}
+
+// contextNotifier records the contexts it is asked to notify.
+type contextNotifier struct{ ecs []types.ErrorContext }
+
+func (c *contextNotifier) Notify(_ context.Context, ec types.ErrorContext, _ types.AnalysisResult) error {
+	c.ecs = append(c.ecs, ec)
+	return nil
+}
+
+func TestEngineAttachesHistory(t *testing.T) {
+	apps := failure("apps")
+	apps.Git.Revision = "main@sha1:aaa"
+	col := &fakeCollector{}
+	n := &contextNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	e.Fingerprinter = state.NewFingerprinter(state.FingerprintOptions{})
+	ctx := context.Background()
+
+	col.set(nil, apps)
+	mustDo(t, e.RunOnce(ctx))
+	mustDo(t, e.RunOnce(ctx)) // still open: no new occurrence
+	col.set(nil)
+	mustDo(t, e.RunOnce(ctx))
+	apps.Git.Revision = "main@sha1:bbb"
+	col.set(nil, apps)
+	mustDo(t, e.RunOnce(ctx))
+
+	if len(n.ecs) != 2 {
+		t.Fatalf("expected two notifications, got %d", len(n.ecs))
+	}
+	first, second := n.ecs[0].History, n.ecs[1].History
+	if first == nil || first.Occurrences != 1 {
+		t.Fatalf("first notification history = %+v", first)
+	}
+	if second == nil || second.Occurrences != 2 || !second.FirstSeen.Equal(first.FirstSeen) {
+		t.Fatalf("second notification history = %+v, first seen %v", second, first.FirstSeen)
+	}
+	if want := []string{"main@sha1:aaa", "main@sha1:bbb"}; !slices.Equal(second.Revisions, want) {
+		t.Errorf("revisions = %v, want %v", second.Revisions, want)
+	}
+}
+
+func TestEngineCountsOccurrencesAcrossRecreatedEngines(t *testing.T) {
+	col := &fakeCollector{}
+	store := state.NewMemoryStore(0, 0)
+	ctx := context.Background()
+	run := func() {
+		t.Helper()
+		mustDo(t, NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, nil, store).RunOnce(ctx))
+	}
+	history := func() types.History {
+		t.Helper()
+		h, err := store.Observe(ctx, state.Fingerprint(failure("apps")), time.Now(), "", false)
+		mustDo(t, err)
+		return h
+	}
+
+	// a CronJob creates a new engine for every run
+	col.set(nil, failure("apps"))
+	run()
+	run()
+	if h := history(); h.Occurrences != 1 {
+		t.Fatalf("a failure that stays open is one occurrence, got %d", h.Occurrences)
+	}
+	col.set(nil)
+	run()
+	col.set(nil, failure("apps"))
+	run()
+	if h := history(); h.Occurrences != 2 {
+		t.Fatalf("a failure that resolved and came back is a new occurrence, got %d", h.Occurrences)
+	}
+}
+
+func TestEngineCollapsesFlappingResources(t *testing.T) {
+	col := &fakeCollector{}
+	kinds := &kindNotifier{}
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{kinds, n}, state.NewMemoryStore(0, 0))
+	e.Flapping = state.FlapPolicy{Window: time.Hour, Threshold: 4}
+	ctx := context.Background()
+
+	// fail and recover four times: transitions 1, 3, 5 and 7 are failures
+	for i := 0; i < 4; i++ {
+		col.set(nil, failure("apps"))
+		mustDo(t, e.RunOnce(ctx))
+		col.set(nil)
+		mustDo(t, e.RunOnce(ctx))
+	}
+	if want := []string{"failure", "failure", "flapping"}; !slices.Equal(kinds.kinds, want) {
+		t.Fatalf("notifications = %v, want %v", kinds.kinds, want)
+	}
+	if want := []int{1, 3, 5}; !slices.Equal(kinds.transitions, want) {
+		t.Errorf("transition counts = %v, want %v", kinds.transitions, want)
+	}
+	if len(n.resolved) != 2 {
+		t.Errorf("failures of a flapping resource must not resolve individually: %v", n.resolved)
+	}
+}
+
+func TestEngineResolvesFlappingResourcesOnceStable(t *testing.T) {
+	col := &fakeCollector{}
+	kinds := &kindNotifier{}
+	n := &recordingNotifier{}
+	store := state.NewMemoryStore(0, 0)
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{kinds, n}, store)
+	window := 500 * time.Millisecond
+	e.Flapping = state.FlapPolicy{Window: window, Threshold: 4}
+	ctx := context.Background()
+	key := state.ResourceKey("prod", failure("apps").Resource)
+
+	for i := 0; i < 4; i++ {
+		col.set(nil, failure("apps"))
+		mustDo(t, e.RunOnce(ctx))
+		col.set(nil)
+		mustDo(t, e.RunOnce(ctx))
+	}
+	if want := []string{"failure", "failure", "flapping"}; !slices.Equal(kinds.kinds, want) {
+		t.Fatalf("notifications = %v, want %v", kinds.kinds, want)
+	}
+	if len(n.resolved) != 2 {
+		t.Fatalf("a flapping resource must not resolve while it flaps: %v", n.resolved)
+	}
+	open, err := store.OpenFailures(ctx)
+	mustDo(t, err)
+	if o, ok := open[key]; !ok || !o.Notified {
+		t.Fatalf("flapping notification must stay open under %s: %v", key, open)
+	}
+
+	// the transitions leave the window: the resource is stable
+	time.Sleep(window)
+	for i := 0; i < 3; i++ {
+		mustDo(t, e.RunOnce(ctx))
+	}
+	if want := []string{"apps", "apps", "apps"}; !slices.Equal(n.resolved, want) {
+		t.Fatalf("resolved = %v, want %v", n.resolved, want)
+	}
+	if ns, _ := store.NotifyState(ctx, key); ns.Notified() {
+		t.Errorf("flapping notification record not cleared: %+v", ns)
+	}
+	if open, _ := store.OpenFailures(ctx); len(open) != 0 {
+		t.Errorf("open failures = %v, want none", open)
+	}
+
+	col.set(nil, failure("apps"))
+	mustDo(t, e.RunOnce(ctx))
+	if got := kinds.kinds[len(kinds.kinds)-1]; got != "failure" {
+		t.Errorf("a failure after the resource was stable is notified as %q", got)
+	}
+}
+
+func TestEngineKeepsOnsetsAcrossRecreatedEngines(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"))
+	store := state.NewMemoryStore(0, 0)
+	kinds := &kindNotifier{}
+	ctx := context.Background()
+
+	// a CronJob creates a new engine for every run
+	for i := 0; i < 4; i++ {
+		e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{kinds}, store)
+		e.Flapping = state.FlapPolicy{Window: time.Hour, Threshold: 2}
+		mustDo(t, e.RunOnce(ctx))
+	}
+	if want := []string{"failure"}; !slices.Equal(kinds.kinds, want) {
+		t.Fatalf("notifications = %v, want %v", kinds.kinds, want)
+	}
+	key := state.ResourceKey("prod", failure("apps").Resource)
+	if n, _ := store.Transitions(ctx, key, time.Now(), time.Hour); n != 1 {
+		t.Errorf("a failure that stays open is one transition, got %d", n)
+	}
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// brokenStore fails every call like an unreachable Redis.
+type brokenStore struct{}
+
+var errStoreDown = errors.New("connection refused")
+
+func (brokenStore) InBackoff(context.Context, string) (bool, error) { return false, errStoreDown }
+func (brokenStore) RegisterFailure(context.Context, string, types.ResourceRef) error {
+	return errStoreDown
+}
+func (brokenStore) RegisterSuccess(context.Context, string) error { return errStoreDown }
+func (brokenStore) NotifyState(context.Context, string) (state.NotifyState, error) {
+	return state.NotifyState{}, errStoreDown
+}
+func (brokenStore) RecordNotification(context.Context, string, state.NotifyState) error {
+	return errStoreDown
+}
+func (brokenStore) Forget(context.Context, string) error { return errStoreDown }
+func (brokenStore) Silence(context.Context, string, state.Silence) error {
+	return errStoreDown
+}
+func (brokenStore) Unsilence(context.Context, string) error { return errStoreDown }
+func (brokenStore) SilenceOf(context.Context, string) (*state.Silence, error) {
+	return nil, errStoreDown
+}
+func (brokenStore) Silences(context.Context) (map[string]state.Silence, error) {
+	return nil, errStoreDown
+}
+func (brokenStore) MarkOpen(context.Context, string, state.OpenFailure) error {
+	return errStoreDown
+}
+func (brokenStore) OpenFailures(context.Context) (map[string]state.OpenFailure, error) {
+	return nil, errStoreDown
+}
+func (brokenStore) Observe(context.Context, string, time.Time, string, bool) (types.History, error) {
+	return types.History{}, errStoreDown
+}
+func (brokenStore) RecordTransition(context.Context, string, time.Time, time.Duration) (int, error) {
+	return 0, errStoreDown
+}
+func (brokenStore) Transitions(context.Context, string, time.Time, time.Duration) (int, error) {
+	return 0, errStoreDown
+}
+func (brokenStore) Reset(context.Context) error { return errStoreDown }
+
+func TestEngineFailsOpenOnStateErrors(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"))
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, brokenStore{})
+
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.notified) != 1 {
+		t.Fatalf("failure must be notified despite state errors: %v", n.notified)
+	}
+
+	// without the store the failures of this process still resolve
+	col.set(nil)
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.resolved) != 1 {
+		t.Fatalf("failure must resolve despite state errors: %v", n.resolved)
+	}
+}
+
+type failingNotifier struct{ err error }
+
+func (f failingNotifier) Notify(context.Context, types.ErrorContext, types.AnalysisResult) error {
+	return f.err
+}
+
+func TestRunOnceStrictness(t *testing.T) {
+	errWebhook := errors.New("webhook returned 500")
+	cases := []struct {
+		strictness Strictness
+		fail       bool
+	}{
+		{StrictnessNone, false},
+		{StrictnessCollectors, false},
+		{StrictnessNotifiers, true},
+		{StrictnessAll, true},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.strictness), func(t *testing.T) {
+			broken, working := &fakeCollector{}, &fakeCollector{}
+			broken.set(errors.New("api down"))
+			working.set(nil, failure("apps"))
+			notifiers := []types.Notifier{&recordingNotifier{}, failingNotifier{err: errWebhook}}
+			e := NewEngine([]ErrorCollector{broken, working}, fakeAnalyzer{}, notifiers, state.NewMemoryStore(0, 0))
+			e.Strictness = tc.strictness
+
+			err := e.RunOnce(context.Background())
+			if (err != nil) != tc.fail {
+				t.Fatalf("RunOnce() = %v, want failure %t", err, tc.fail)
+			}
+			if err == nil {
+				return
+			}
+			var runErr *RunError
+			if !errors.As(err, &runErr) || !errors.Is(err, errWebhook) {
+				t.Fatalf("want a *RunError wrapping the notifier error, got %v", err)
+			}
+			if runErr.Collectors != 2 || runErr.CollectorFailures != 1 || runErr.Analyses != 1 ||
+				runErr.Notifications != 2 || runErr.NotificationFailures != 1 || len(runErr.Errors) != 2 {
+				t.Errorf("unexpected counts: %+v", runErr)
+			}
+			if got := runErr.Errors[1]; got.Stage != StageNotify || got.Source != "reconcile.failingNotifier" || got.Resource.Name != "apps" {
+				t.Errorf("unexpected notify error: %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseStrictness(t *testing.T) {
+	if s, err := ParseStrictness(" Notifiers "); err != nil || s != StrictnessNotifiers {
+		t.Errorf("ParseStrictness() = %q, %v", s, err)
+	}
+	if _, err := ParseStrictness("sometimes"); err == nil {
+		t.Error("unknown strictness must be rejected")
+	}
+}
+
+type hangingNotifier struct{}
+
+func (hangingNotifier) Notify(ctx context.Context, _ types.ErrorContext, _ types.AnalysisResult) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestEngineWorkerPoolBoundsSlowNotifiers(t *testing.T) {
+	col := &fakeCollector{}
+	var ecs []types.ErrorContext
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		ecs = append(ecs, failure(name))
+	}
+	// duplicates within one cycle are processed once
+	col.set(nil, append(ecs, failure("a"))...)
+
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{hangingNotifier{}, n}, state.NewMemoryStore(0, 0))
+	e.Concurrency = 4
+	e.NotifyTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 8 items * 50ms sequentially would take 400ms; 4 workers need ~100ms
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("cycle took %s, worker pool did not parallelize", elapsed)
+	}
+	if len(n.notified) != len(ecs) {
+		t.Errorf("expected %d notifications after the hanging notifier timed out, got %v", len(ecs), n.notified)
+	}
+}
+
+type namedCollector struct{ fakeCollector }
+
+func (*namedCollector) Name() string { return "fake" }
+
+func TestEngineRecordsMetrics(t *testing.T) {
+	col := &namedCollector{}
+	col.set(nil, failure("apps"), failure("apps"))
+	n := &recordingNotifier{}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n}, state.NewMemoryStore(0, 0))
+	channel := channelOf(n)
+
+	runs := testutil.ToFloat64(metrics.CollectorRuns.WithLabelValues("fake", "success"))
+	collected := testutil.ToFloat64(metrics.ErrorsCollected.WithLabelValues("fake"))
+	dupes := testutil.ToFloat64(metrics.ErrorsSkipped.WithLabelValues("duplicate"))
+	sent := testutil.ToFloat64(metrics.Notifications.WithLabelValues(channel, "failure", "success"))
+	resolved := testutil.ToFloat64(metrics.Notifications.WithLabelValues(channel, "resolved", "success"))
+
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(metrics.CollectorRuns.WithLabelValues("fake", "success")) - runs; got != 1 {
+		t.Errorf("collector runs = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.ErrorsCollected.WithLabelValues("fake")) - collected; got != 2 {
+		t.Errorf("errors collected = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.ErrorsSkipped.WithLabelValues("duplicate")) - dupes; got != 1 {
+		t.Errorf("duplicates skipped = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.Notifications.WithLabelValues(channel, "failure", "success")) - sent; got != 1 {
+		t.Errorf("notifications = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.OpenFailures); got != 1 {
+		t.Errorf("open failures = %v, want 1", got)
+	}
+
+	col.set(nil)
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(metrics.Notifications.WithLabelValues(channel, "resolved", "success")) - resolved; got != 1 {
+		t.Errorf("resolved notifications = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.OpenFailures); got != 0 {
+		t.Errorf("open failures = %v, want 0", got)
+	}
+	if testutil.ToFloat64(metrics.LastSuccessfulCycle) == 0 {
+		t.Error("last successful cycle not recorded")
+	}
+}
+
+func TestRunWithReport(t *testing.T) {
+	col := &namedCollector{}
+	col.set(nil, failure("apps"), failure("apps"), failure("infra"))
+	n := &recordingNotifier{}
+	broken := failingNotifier{err: errors.New("webhook returned 500")}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{n, broken}, state.NewMemoryStore(0, 0))
+	e.ReportPath = filepath.Join(t.TempDir(), "report.json")
+	ctx := context.Background()
+
+	report, err := e.RunWithReport(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Collectors) != 1 || report.Collectors[0].Name != "fake" || report.Collectors[0].Contexts != 3 {
+		t.Errorf("unexpected collectors: %+v", report.Collectors)
+	}
+	if report.Contexts != 2 || report.Duplicates != 1 || report.Analyses != 2 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if got := report.Channels[channelOf(n)]; got.Sent != 2 || got.Failed != 0 {
+		t.Errorf("recording channel = %+v, want 2 sent", got)
+	}
+	if got := report.Channels[channelOf(broken)]; got.Sent != 0 || got.Failed != 2 {
+		t.Errorf("failing channel = %+v, want 2 failed", got)
+	}
+	item := report.Items[0]
+	if item.Resource.Name != "apps" || item.Outcome != OutcomeNotified || len(item.Notifications) != 2 ||
+		item.Notifications[1].Status != StatusFailed || item.Notifications[1].Error == "" {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	col.set(nil, failure("infra"))
+	report, err = e.RunWithReport(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Skipped["notified"] != 1 || report.Analyses != 0 || report.Items[0].SkipReason != "notified" {
+		t.Errorf("infra must be skipped as already notified: %+v", report)
+	}
+	if len(report.Resolved) != 1 || report.Resolved[0].Resource.Name != "apps" || report.Resolved[0].Outcome != OutcomeResolved {
+		t.Errorf("apps must resolve: %+v", report.Resolved)
+	}
+
+	data, err := os.ReadFile(e.ReportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written RunReport
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Contexts != 1 || len(written.Resolved) != 1 {
+		t.Errorf("report file does not hold the last cycle: %s", data)
+	}
+}
+
+func TestEngineLogsFailureAttributes(t *testing.T) {
+	col := &fakeCollector{}
+	col.set(nil, failure("apps"))
+	broken := failingNotifier{err: errors.New("webhook returned 500")}
+	e := NewEngine([]ErrorCollector{col}, fakeAnalyzer{}, []types.Notifier{broken}, state.NewMemoryStore(0, 0))
+	var buf bytes.Buffer
+	e.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	_ = e.RunOnce(context.Background())
+
+	var found bool
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if entry["msg"] != "notification failed" {
+			continue
+		}
+		found = true
+		want := map[string]any{
+			"fingerprint": e.fingerprint(failure("apps")),
+			"cluster":     "prod",
+			"kind":        string(types.FluxResourceKindKustomization),
+			"namespace":   "flux-system",
+			"name":        "apps",
+			"channel":     channelOf(broken),
+		}
+		for k, v := range want {
+			if entry[k] != v {
+				t.Errorf("%s = %v, want %v", k, entry[k], v)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("no notification failure logged: %s", buf.String())
+	}
+}
+
+// resetCounter counts Reset calls.
+type resetCounter struct{ resets int }
+
+func (r *resetCounter) Reset() { r.resets++ }
+
+func TestEngineResetsCachesEveryCycle(t *testing.T) {
+	cache := &resetCounter{}
+	e := NewEngine([]ErrorCollector{&fakeCollector{}}, fakeAnalyzer{}, nil, state.NewMemoryStore(0, 0))
+	e.Caches = []CycleCache{cache}
+	for i := 0; i < 2; i++ {
+		mustDo(t, e.RunOnce(context.Background()))
+	}
+	if cache.resets != 2 {
+		t.Errorf("resets = %d, want 2", cache.resets)
+	}
+}
@@ -0,0 +1,169 @@
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Stages of a cycle reported in a StageError.
+const (
+	StageCollect = "collect"
+	StageState   = "state"
+	StageAnalyze = "analyze"
+	StageNotify  = "notify"
+	StageResolve = "resolve"
+)
+
+// StageError is a failure in one stage of a cycle.
+type StageError struct {
+	Stage string
+	// Source is the collector or notification channel; empty for other stages.
+	Source      string
+	Fingerprint string
+	Resource    types.ResourceRef
+	Err         error
+}
+
+func (e StageError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Stage)
+	if e.Source != "" {
+		b.WriteString(" " + e.Source)
+	}
+	if e.Resource.Name != "" {
+		fmt.Fprintf(&b, " %s/%s", e.Resource.Namespace, e.Resource.Name)
+	}
+	b.WriteString(": " + e.Err.Error())
+	return b.String()
+}
+
+func (e StageError) Unwrap() error { return e.Err }
+
+// RunError aggregates what went wrong in a cycle. RunOnce returns it when the
+// engine's Strictness considers the failures fatal.
+type RunError struct {
+	Collectors           int
+	CollectorFailures    int
+	Analyses             int
+	AnalysisFailures     int
+	Notifications        int
+	NotificationFailures int
+	// Errors lists every failure in stage order.
+	Errors []StageError
+}
+
+func (e *RunError) Error() string {
+	msg := fmt.Sprintf("reconcile cycle failed: %d/%d collectors, %d/%d analyses, %d/%d notifications failed",
+		e.CollectorFailures, e.Collectors, e.AnalysisFailures, e.Analyses, e.NotificationFailures, e.Notifications)
+	switch n := len(e.Errors); {
+	case n == 1:
+		msg += ": " + e.Errors[0].Error()
+	case n > 1:
+		msg += fmt.Sprintf(": %s (and %d more)", e.Errors[0].Error(), n-1)
+	}
+	return msg
+}
+
+// Unwrap returns the StageErrors so errors.Is and errors.As see the causes.
+func (e *RunError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, se := range e.Errors {
+		errs[i] = se
+	}
+	return errs
+}
+
+// newRunError aggregates the failures of a cycle in stage order.
+func newRunError(runs []collectorRun, results []itemResult, resolutions []resolution) *RunError {
+	e := &RunError{Collectors: len(runs)}
+	for _, run := range runs {
+		if run.err != nil {
+			e.CollectorFailures++
+			e.Errors = append(e.Errors, StageError{Stage: StageCollect, Source: run.name, Err: run.err})
+		}
+	}
+	for _, r := range results {
+		ref := r.item.ec.Resource
+		for _, err := range r.stateErrs {
+			e.Errors = append(e.Errors, StageError{Stage: StageState, Fingerprint: r.item.fp, Resource: ref, Err: err})
+		}
+		if r.analyzed {
+			e.Analyses++
+		}
+		if r.analyzeErr != nil {
+			e.AnalysisFailures++
+			e.Errors = append(e.Errors, StageError{Stage: StageAnalyze, Fingerprint: r.item.fp, Resource: ref, Err: r.analyzeErr})
+		}
+		for _, d := range r.deliveries {
+			e.Notifications++
+			if d.err != nil {
+				e.NotificationFailures++
+				e.Errors = append(e.Errors, StageError{Stage: StageNotify, Source: d.channel, Fingerprint: r.item.fp, Resource: ref, Err: d.err})
+			}
+		}
+	}
+	for _, res := range resolutions {
+		ref := res.ec.Resource
+		for _, d := range res.deliveries {
+			if d.err != nil {
+				e.Errors = append(e.Errors, StageError{Stage: StageResolve, Source: d.channel, Fingerprint: res.fp, Resource: ref, Err: d.err})
+			}
+		}
+		for _, err := range res.stateErrs {
+			e.Errors = append(e.Errors, StageError{Stage: StageState, Fingerprint: res.fp, Resource: ref, Err: err})
+		}
+	}
+	return e
+}
+
+// Strictness selects which failures make RunOnce return an error. Failures
+// are logged regardless.
+type Strictness string
+
+const (
+	// StrictnessNone never fails a cycle.
+	StrictnessNone Strictness = "none"
+	// StrictnessCollectors fails a cycle when every collector failed.
+	StrictnessCollectors Strictness = "collectors"
+	// StrictnessNotifiers also fails a cycle when any notification, including
+	// a resolve notification, failed.
+	StrictnessNotifiers Strictness = "notifiers"
+	// StrictnessAll fails a cycle on any collector, state store, analysis,
+	// notification or resolve error.
+	StrictnessAll Strictness = "all"
+)
+
+// ParseStrictness parses "none", "collectors", "notifiers" or "all".
+func ParseStrictness(s string) (Strictness, error) {
+	switch st := Strictness(strings.ToLower(strings.TrimSpace(s))); st {
+	case StrictnessNone, StrictnessCollectors, StrictnessNotifiers, StrictnessAll:
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown strictness %q (want none, collectors, notifiers or all)", s)
+}
+
+// failedIn reports whether a failure was recorded in stage.
+func (e *RunError) failedIn(stage string) bool {
+	for _, se := range e.Errors {
+		if se.Stage == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// fatal reports whether s fails a cycle that ended with e.
+func (s Strictness) fatal(e *RunError) bool {
+	allCollectorsFailed := e.Collectors > 0 && e.CollectorFailures == e.Collectors
+	switch s {
+	case StrictnessNone:
+		return false
+	case StrictnessNotifiers:
+		return allCollectorsFailed || e.NotificationFailures > 0 || e.failedIn(StageResolve)
+	case StrictnessAll:
+		return len(e.Errors) > 0
+	}
+	return allCollectorsFailed
+}
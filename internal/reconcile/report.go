@@ -0,0 +1,232 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Item outcomes in a RunReport.
+const (
+	OutcomeSkipped        = "skipped"
+	OutcomeAnalysisFailed = "analysisFailed"
+	// OutcomeAnalyzed means the failure was analyzed but no notifier is configured.
+	OutcomeAnalyzed     = "analyzed"
+	OutcomeNotified     = "notified"
+	OutcomeNotifyFailed = "notifyFailed"
+	OutcomeResolved     = "resolved"
+)
+
+// Notification statuses in a RunReport.
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+)
+
+// RunReport is the machine-readable record of one cycle: what was collected,
+// skipped, analyzed, notified and resolved, and how long it took. Failures
+// pushed through Engine.Process outside a cycle are not part of any report.
+type RunReport struct {
+	Started         time.Time         `json:"started"`
+	DurationSeconds float64           `json:"durationSeconds"`
+	Collectors      []CollectorReport `json:"collectors"`
+	// Contexts counts the deduplicated ErrorContexts; Duplicates the dropped ones.
+	Contexts   int `json:"contexts"`
+	Duplicates int `json:"duplicates"`
+	// Skipped counts contexts that were not analyzed, by reason
+	// (ready, silenced, backoff, notified, flapping).
+	Skipped          map[string]int           `json:"skipped"`
+	Analyses         int                      `json:"analyses"`
+	AnalysisFailures int                      `json:"analysisFailures"`
+	Channels         map[string]ChannelReport `json:"channels"`
+	Items            []ItemReport             `json:"items"`
+	Resolved         []ItemReport             `json:"resolved"`
+	// Error is the *RunError returned by the cycle, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// CollectorReport describes one collector run.
+type CollectorReport struct {
+	Name            string  `json:"name"`
+	Contexts        int     `json:"contexts"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// ChannelReport counts the notifications of a channel, including resolves.
+type ChannelReport struct {
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+}
+
+// ItemReport describes what happened to one fingerprint.
+type ItemReport struct {
+	Fingerprint string            `json:"fingerprint"`
+	Resource    types.ResourceRef `json:"resource"`
+	Outcome     string            `json:"outcome"`
+	SkipReason  string            `json:"skipReason,omitempty"`
+	// AnalysisSeconds is set when the context was analyzed.
+	AnalysisSeconds float64              `json:"analysisSeconds,omitempty"`
+	AnalysisError   string               `json:"analysisError,omitempty"`
+	Notifications   []NotificationReport `json:"notifications,omitempty"`
+	StateErrors     []string             `json:"stateErrors,omitempty"`
+}
+
+// NotificationReport describes one Notify or Resolve call.
+type NotificationReport struct {
+	Channel         string  `json:"channel"`
+	Kind            string  `json:"kind"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// newRunReport builds the report of a cycle that started at start.
+func newRunReport(start time.Time, runs []collectorRun, duplicates int, results []itemResult, resolutions []resolution) *RunReport {
+	r := &RunReport{
+		Started:    start,
+		Collectors: make([]CollectorReport, 0, len(runs)),
+		Contexts:   len(results),
+		Duplicates: duplicates,
+		Skipped:    make(map[string]int),
+		Channels:   make(map[string]ChannelReport),
+		Items:      make([]ItemReport, 0, len(results)),
+		Resolved:   make([]ItemReport, 0, len(resolutions)),
+	}
+	for _, run := range runs {
+		r.Collectors = append(r.Collectors, CollectorReport{
+			Name:            run.name,
+			Contexts:        len(run.ecs),
+			DurationSeconds: run.duration.Seconds(),
+			Error:           errorString(run.err),
+		})
+	}
+	for _, res := range results {
+		item := ItemReport{
+			Fingerprint: res.item.fp,
+			Resource:    res.item.ec.Resource,
+			StateErrors: errorStrings(res.stateErrs),
+		}
+		if reason := res.skipReason(); reason != "" {
+			item.Outcome, item.SkipReason = OutcomeSkipped, reason
+			r.Skipped[reason]++
+		}
+		if res.analyzed {
+			r.Analyses++
+			item.AnalysisSeconds = res.analysis.Seconds()
+			item.AnalysisError = errorString(res.analyzeErr)
+		}
+		if res.analyzeErr != nil {
+			r.AnalysisFailures++
+			item.Outcome = OutcomeAnalysisFailed
+		}
+		if item.Outcome == "" {
+			item.Outcome = deliveryOutcome(res.deliveries)
+		}
+		item.Notifications = r.addDeliveries(res.deliveries)
+		r.Items = append(r.Items, item)
+	}
+	for _, res := range resolutions {
+		r.Resolved = append(r.Resolved, ItemReport{
+			Fingerprint:   res.fp,
+			Resource:      res.ec.Resource,
+			Outcome:       OutcomeResolved,
+			Notifications: r.addDeliveries(res.deliveries),
+			StateErrors:   errorStrings(res.stateErrs),
+		})
+	}
+	r.DurationSeconds = time.Since(start).Seconds()
+	return r
+}
+
+// addDeliveries counts ds per channel and returns their reports.
+func (r *RunReport) addDeliveries(ds []delivery) []NotificationReport {
+	var out []NotificationReport
+	for _, d := range ds {
+		n := NotificationReport{
+			Channel:         d.channel,
+			Kind:            d.kind,
+			Status:          StatusSent,
+			DurationSeconds: d.duration.Seconds(),
+			Error:           errorString(d.err),
+		}
+		c := r.Channels[d.channel]
+		if d.err != nil {
+			n.Status = StatusFailed
+			c.Failed++
+		} else {
+			c.Sent++
+		}
+		r.Channels[d.channel] = c
+		out = append(out, n)
+	}
+	return out
+}
+
+// deliveryOutcome classifies an analyzed context by its notifications.
+func deliveryOutcome(ds []delivery) string {
+	if len(ds) == 0 {
+		return OutcomeAnalyzed
+	}
+	for _, d := range ds {
+		if d.err == nil {
+			return OutcomeNotified
+		}
+	}
+	return OutcomeNotifyFailed
+}
+
+// publish logs the report as JSON and writes it to ReportPath when set.
+func (e *Engine) publish(r *RunReport) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		e.logger().Error("encode run report", "error", err)
+		return
+	}
+	e.logger().Info("run report", "report", json.RawMessage(data))
+	if e.ReportPath == "" {
+		return
+	}
+	if err := writeFileAtomic(e.ReportPath, append(data, '\n')); err != nil {
+		e.logger().Error("write run report", "path", e.ReportPath, "error", err)
+	}
+}
+
+// writeFileAtomic replaces path with data so readers never see a partial report.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func errorStrings(errs []error) []string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, err.Error())
+	}
+	return out
+}
@@ -2,7 +2,7 @@ package reconcile
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -10,6 +10,8 @@ import (
 type Runner struct {
 	Engine   Reconciler
 	Interval time.Duration
+	// OnCycle is called after every completed RunOnce with its result (optional).
+	OnCycle func(err error)
 }
 
 // NewRunner creates a new ticker-based runner.
@@ -22,11 +24,11 @@ func NewRunner(engine Reconciler, interval time.Duration) *Runner {
 
 // Start begins the reconciliation loop. Blocks until context is canceled.
 func (r *Runner) Start(ctx context.Context) error {
-	log.Printf("starting reconciliation loop with interval %s", r.Interval)
+	slog.Info("starting reconciliation loop", "interval", r.Interval)
 
 	// Run once immediately
-	if err := r.Engine.RunOnce(ctx); err != nil {
-		log.Printf("initial reconciliation failed: %v", err)
+	if err := r.runOnce(ctx); err != nil {
+		slog.Error("initial reconciliation failed", "error", err)
 	}
 
 	ticker := time.NewTicker(r.Interval)
@@ -35,12 +37,20 @@ func (r *Runner) Start(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("reconciliation loop stopped")
+			slog.Info("reconciliation loop stopped")
 			return ctx.Err()
 		case <-ticker.C:
-			if err := r.Engine.RunOnce(ctx); err != nil {
-				log.Printf("reconciliation cycle failed: %v", err)
+			if err := r.runOnce(ctx); err != nil {
+				slog.Error("reconciliation cycle failed", "error", err)
 			}
 		}
 	}
 }
+
+func (r *Runner) runOnce(ctx context.Context) error {
+	err := r.Engine.RunOnce(ctx)
+	if r.OnCycle != nil && ctx.Err() == nil {
+		r.OnCycle(err)
+	}
+	return err
+}
@@ -0,0 +1,207 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Origins of a Signal.
+const (
+	OriginEvent     = "event"
+	OriginCondition = "condition"
+)
+
+// Action decides what happens to a signal matched by a rule.
+type Action string
+
+const (
+	ActionInclude Action = "include"
+	ActionExclude Action = "exclude"
+)
+
+// Signal is an observed failure candidate that rules are evaluated against.
+type Signal struct {
+	Kind      types.FluxResourceKind
+	Namespace string
+	Name      string
+	Reason    string
+	Message   string
+	// Labels of the Flux object; nil when the collector only sees events.
+	Labels map[string]string
+	// Origin is OriginEvent for Kubernetes Events and OriginCondition for status conditions.
+	Origin string
+}
+
+// Rule matches a Signal when every configured matcher matches.
+// Reason and Message are regular expressions; all other matchers are exact.
+type Rule struct {
+	Name       string            `json:"name"`
+	Action     Action            `json:"action"`
+	Kinds      []string          `json:"kinds,omitempty"`
+	Origins    []string          `json:"origins,omitempty"`
+	Namespaces []string          `json:"namespaces,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Message    string            `json:"message,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+
+	reason  *regexp.Regexp
+	message *regexp.Regexp
+}
+
+// Set is an ordered list of compiled rules.
+// A signal is a failure if no exclude rule and at least one include rule matches.
+type Set struct {
+	rules []Rule
+}
+
+// File is the on-disk format of FLUXBRAIN_RULES_FILE (YAML or JSON).
+type File struct {
+	// IncludeDefaults appends the default rule set after the configured rules.
+	IncludeDefaults bool   `json:"includeDefaults"`
+	Rules           []Rule `json:"rules"`
+}
+
+// DefaultRules mirror fluxbrain's historic hardcoded failure hints.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			Name:    "failure-reason",
+			Action:  ActionInclude,
+			Origins: []string{OriginEvent},
+			Reason:  `(?i)reconciliationfailed|ready`,
+		},
+		{
+			Name:    "failure-message",
+			Action:  ActionInclude,
+			Origins: []string{OriginEvent},
+			Message: `(?i)reconciliation failed|apply failed|health check failed|dependency not ready`,
+		},
+		{
+			// status collectors only report objects with Ready=False
+			Name:    "not-ready-condition",
+			Action:  ActionInclude,
+			Origins: []string{OriginCondition},
+		},
+	}
+}
+
+var defaultSet = func() *Set {
+	s, err := New(DefaultRules())
+	if err != nil {
+		panic(err) // default rules are static and must compile
+	}
+	return s
+}()
+
+// Default returns the compiled default rule set.
+func Default() *Set {
+	return defaultSet
+}
+
+// New compiles rules into a Set.
+func New(rules []Rule) (*Set, error) {
+	compiled := make([]Rule, 0, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i)
+		}
+		switch r.Action {
+		case ActionInclude, ActionExclude:
+		case "":
+			r.Action = ActionInclude
+		default:
+			return nil, fmt.Errorf("rule %q: invalid action %q", r.Name, r.Action)
+		}
+		var err error
+		if r.reason, err = compile(r.Reason); err != nil {
+			return nil, fmt.Errorf("rule %q: reason: %w", r.Name, err)
+		}
+		if r.message, err = compile(r.Message); err != nil {
+			return nil, fmt.Errorf("rule %q: message: %w", r.Name, err)
+		}
+		compiled = append(compiled, r)
+	}
+	return &Set{rules: compiled}, nil
+}
+
+// Load reads a rule file. An empty path returns the default rule set.
+func Load(path string) (*Set, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("parse rules file %s: %w", path, err)
+	}
+	rules := f.Rules
+	if f.IncludeDefaults {
+		rules = append(rules, DefaultRules()...)
+	}
+	return New(rules)
+}
+
+// Match reports whether the signal is classified as a failure.
+func (s *Set) Match(sig Signal) bool {
+	included := false
+	for i := range s.rules {
+		r := &s.rules[i]
+		if !r.matches(sig) {
+			continue
+		}
+		if r.Action == ActionExclude {
+			return false
+		}
+		included = true
+	}
+	return included
+}
+
+func (r *Rule) matches(sig Signal) bool {
+	if len(r.Kinds) > 0 && !containsFold(r.Kinds, string(sig.Kind)) {
+		return false
+	}
+	if len(r.Origins) > 0 && !containsFold(r.Origins, sig.Origin) {
+		return false
+	}
+	if len(r.Namespaces) > 0 && !containsFold(r.Namespaces, sig.Namespace) {
+		return false
+	}
+	if r.reason != nil && !r.reason.MatchString(sig.Reason) {
+		return false
+	}
+	if r.message != nil && !r.message.MatchString(sig.Message) {
+		return false
+	}
+	for k, v := range r.Labels {
+		if got, ok := sig.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func compile(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
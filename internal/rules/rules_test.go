@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// TestDefaultRulesPinLegacyHints pins the behavior of the former hardcoded
+// isReconciliationFailure check for event signals.
+func TestDefaultRulesPinLegacyHints(t *testing.T) {
+	cases := []struct {
+		reason  string
+		message string
+		want    bool
+	}{
+		{"ReconciliationFailed", "", true},
+		{"reconciliationfailed", "anything", true},
+		{"Ready", "Applied revision main@sha1:abc", true},
+		{"DependencyNotReady", "dependency 'flux-system/infra' is not ready", true},
+		{"HealthCheckFailed", "Health check failed after 30s", true},
+		{"BuildFailed", "apply failed: error validating data", true},
+		{"Progressing", "Reconciliation failed after 2s", true},
+		{"ArtifactFailed", "kustomization path not found", false},
+		{"Progressing", "Reconciliation in progress", false},
+		{"", "", false},
+	}
+
+	set := Default()
+	for _, tc := range cases {
+		sig := Signal{Kind: types.FluxResourceKindKustomization, Reason: tc.reason, Message: tc.message, Origin: OriginEvent}
+		if got := set.Match(sig); got != tc.want {
+			t.Errorf("Match(%q, %q) = %t, want %t", tc.reason, tc.message, got, tc.want)
+		}
+	}
+
+	cond := Signal{Kind: types.FluxResourceKindHelmRelease, Reason: "ArtifactFailed", Origin: OriginCondition}
+	if !set.Match(cond) {
+		t.Error("default rules should keep every not-Ready condition")
+	}
+}
+
+func TestRulesExcludeWins(t *testing.T) {
+	set, err := New([]Rule{
+		{Name: "any-event", Action: ActionInclude, Origins: []string{OriginEvent}},
+		{Name: "ready-transition", Action: ActionExclude, Reason: `^Ready$`},
+		{Name: "sandbox", Action: ActionExclude, Namespaces: []string{"sandbox"}},
+		{Name: "team", Action: ActionExclude, Labels: map[string]string{"team": "playground"}},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	cases := map[string]struct {
+		sig  Signal
+		want bool
+	}{
+		"included":         {Signal{Reason: "DependencyNotReady", Namespace: "apps", Origin: OriginEvent}, true},
+		"ready excluded":   {Signal{Reason: "Ready", Namespace: "apps", Origin: OriginEvent}, false},
+		"namespace":        {Signal{Reason: "BuildFailed", Namespace: "sandbox", Origin: OriginEvent}, false},
+		"labels":           {Signal{Reason: "BuildFailed", Labels: map[string]string{"team": "playground"}, Origin: OriginEvent}, false},
+		"labels unknown":   {Signal{Reason: "BuildFailed", Origin: OriginEvent}, true},
+		"no include match": {Signal{Reason: "BuildFailed", Origin: OriginCondition}, false},
+	}
+	for name, tc := range cases {
+		if got := set.Match(tc.sig); got != tc.want {
+			t.Errorf("%s: Match = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `includeDefaults: true
+rules:
+  - name: ignore-ready
+    action: exclude
+    kinds: [Kustomization]
+    reason: "^Ready$"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if set.Match(Signal{Kind: types.FluxResourceKindKustomization, Reason: "Ready", Origin: OriginEvent}) {
+		t.Error("configured exclude rule should drop Ready transitions")
+	}
+	if !set.Match(Signal{Kind: types.FluxResourceKindKustomization, Reason: "ReconciliationFailed", Origin: OriginEvent}) {
+		t.Error("includeDefaults should keep the default include rules")
+	}
+
+	if err := os.WriteFile(path, []byte("rules:\n  - reason: \"(\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("invalid regex should fail to load")
+	}
+}
@@ -0,0 +1,265 @@
+package state
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// Backoff strategies.
+const (
+	StrategyLinear      = "linear"
+	StrategyExponential = "exponential"
+	StrategyFibonacci   = "fibonacci"
+)
+
+// Jitter modes.
+const (
+	JitterNone         = "none"
+	JitterFull         = "full"
+	JitterDecorrelated = "decorrelated"
+)
+
+// BackoffPolicy computes how long a fingerprint stays in backoff.
+type BackoffPolicy interface {
+	// Delay returns the backoff after the given number of consecutive
+	// failures (>= 1); prev is the previous delay (0 on the first failure).
+	Delay(failures int, prev time.Duration) time.Duration
+}
+
+// Backoff is the built-in BackoffPolicy.
+//
+// Jitter spreads replicas that failed at the same instant:
+//   - full: uniform in [0, d)
+//   - decorrelated: uniform in [Base, max(d, 3*prev)), so consecutive delays
+//     are independent while still growing with the strategy
+//
+// The result never exceeds Max.
+type Backoff struct {
+	Strategy   string
+	Base       time.Duration
+	Max        time.Duration
+	Multiplier float64 // exponential only; default 2
+	Jitter     string
+	// Rand returns a number in [0, 1); nil uses math/rand.
+	Rand func() float64
+}
+
+// defaultBackoff is what store constructors use: exponential without jitter,
+// 30s base and 1h cap unless given.
+func defaultBackoff(base, maxBackoff time.Duration) *BackoffPolicies {
+	if base == 0 {
+		base = 30 * time.Second
+	}
+	if maxBackoff == 0 {
+		maxBackoff = 1 * time.Hour
+	}
+	return NewBackoffPolicies(NewBackoff(StrategyExponential, base, maxBackoff))
+}
+
+// NewBackoff returns a policy without jitter, as used by the store constructors.
+func NewBackoff(strategy string, base, maxBackoff time.Duration) *Backoff {
+	return &Backoff{Strategy: strategy, Base: base, Max: maxBackoff, Multiplier: 2, Jitter: JitterNone}
+}
+
+// Validate checks strategy, jitter and durations.
+func (b *Backoff) Validate() error {
+	switch b.Strategy {
+	case StrategyLinear, StrategyExponential, StrategyFibonacci:
+	default:
+		return fmt.Errorf("unknown backoff strategy %q", b.Strategy)
+	}
+	switch b.Jitter {
+	case "", JitterNone, JitterFull, JitterDecorrelated:
+	default:
+		return fmt.Errorf("unknown backoff jitter %q", b.Jitter)
+	}
+	if b.Base <= 0 || b.Max < b.Base {
+		return fmt.Errorf("backoff needs 0 < base <= max (base %s, max %s)", b.Base, b.Max)
+	}
+	if b.Strategy == StrategyExponential && b.Multiplier != 0 && b.Multiplier < 1 {
+		return fmt.Errorf("backoff multiplier must be at least 1, got %g", b.Multiplier)
+	}
+	return nil
+}
+
+// Delay implements BackoffPolicy.
+func (b *Backoff) Delay(failures int, prev time.Duration) time.Duration {
+	if failures < 1 {
+		failures = 1
+	}
+	d := b.raw(failures)
+	switch b.Jitter {
+	case JitterFull:
+		d = time.Duration(b.random() * float64(d))
+	case JitterDecorrelated:
+		upper := d
+		if 3*prev > upper {
+			upper = 3 * prev
+		}
+		if upper > b.Max {
+			upper = b.Max
+		}
+		d = b.Base + time.Duration(b.random()*float64(upper-b.Base))
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// raw is the delay of the strategy without jitter, capped at Max.
+func (b *Backoff) raw(failures int) time.Duration {
+	var factor float64
+	switch b.Strategy {
+	case StrategyExponential:
+		mult := b.Multiplier
+		if mult == 0 {
+			mult = 2
+		}
+		factor = math.Pow(mult, float64(failures-1))
+	case StrategyFibonacci:
+		prev, cur := 0.0, 1.0
+		for i := 1; i < failures && cur*float64(b.Base) < float64(b.Max); i++ {
+			prev, cur = cur, prev+cur
+		}
+		factor = cur
+	default:
+		factor = float64(failures)
+	}
+	d := factor * float64(b.Base)
+	if d >= float64(b.Max) {
+		return b.Max
+	}
+	return time.Duration(d)
+}
+
+func (b *Backoff) random() float64 {
+	if b.Rand != nil {
+		return b.Rand()
+	}
+	return rand.Float64()
+}
+
+// BackoffPolicies selects a policy per resource. Namespace overrides take
+// precedence over kind overrides, which take precedence over Default.
+type BackoffPolicies struct {
+	Default    BackoffPolicy
+	Kinds      map[types.FluxResourceKind]BackoffPolicy
+	Namespaces map[string]BackoffPolicy
+}
+
+// NewBackoffPolicies returns policies that apply def to every resource.
+func NewBackoffPolicies(def BackoffPolicy) *BackoffPolicies {
+	return &BackoffPolicies{Default: def}
+}
+
+// For returns the policy for ref.
+func (p *BackoffPolicies) For(ref types.ResourceRef) BackoffPolicy {
+	if policy, ok := p.Namespaces[ref.Namespace]; ok {
+		return policy
+	}
+	if policy, ok := p.Kinds[ref.Kind]; ok {
+		return policy
+	}
+	return p.Default
+}
+
+// Delay returns the delay of ref's policy.
+func (p *BackoffPolicies) Delay(ref types.ResourceRef, failures int, prev time.Duration) time.Duration {
+	return p.For(ref).Delay(failures, prev)
+}
+
+// BackoffSpec is the file form of a Backoff. Unset fields inherit from the default.
+type BackoffSpec struct {
+	Strategy   string           `json:"strategy,omitempty"`
+	Base       *metav1.Duration `json:"base,omitempty"`
+	Max        *metav1.Duration `json:"max,omitempty"`
+	Multiplier float64          `json:"multiplier,omitempty"`
+	Jitter     string           `json:"jitter,omitempty"`
+}
+
+// BackoffFile is the YAML/JSON format of FLUXBRAIN_BACKOFF_FILE.
+type BackoffFile struct {
+	Default    BackoffSpec            `json:"default,omitempty"`
+	Kinds      map[string]BackoffSpec `json:"kinds,omitempty"`
+	Namespaces map[string]BackoffSpec `json:"namespaces,omitempty"`
+}
+
+// LoadBackoffPolicies builds policies from def and, when path is set, the
+// overrides in that file.
+func LoadBackoffPolicies(path string, def Backoff) (*BackoffPolicies, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	policies := NewBackoffPolicies(&def)
+	if path == "" {
+		return policies, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read backoff file: %w", err)
+	}
+	var file BackoffFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("parse backoff file %s: %w", path, err)
+	}
+
+	base, err := file.Default.apply(def)
+	if err != nil {
+		return nil, fmt.Errorf("backoff file %s: default: %w", path, err)
+	}
+	policies.Default = base
+	if len(file.Kinds) > 0 {
+		policies.Kinds = make(map[types.FluxResourceKind]BackoffPolicy, len(file.Kinds))
+	}
+	for kind, spec := range file.Kinds {
+		b, err := spec.apply(*base)
+		if err != nil {
+			return nil, fmt.Errorf("backoff file %s: kind %s: %w", path, kind, err)
+		}
+		policies.Kinds[types.FluxResourceKind(kind)] = b
+	}
+	if len(file.Namespaces) > 0 {
+		policies.Namespaces = make(map[string]BackoffPolicy, len(file.Namespaces))
+	}
+	for ns, spec := range file.Namespaces {
+		b, err := spec.apply(*base)
+		if err != nil {
+			return nil, fmt.Errorf("backoff file %s: namespace %s: %w", path, ns, err)
+		}
+		policies.Namespaces[ns] = b
+	}
+	return policies, nil
+}
+
+// apply overlays the spec on a copy of b.
+func (s BackoffSpec) apply(b Backoff) (*Backoff, error) {
+	if s.Strategy != "" {
+		b.Strategy = s.Strategy
+	}
+	if s.Base != nil {
+		b.Base = s.Base.Duration
+	}
+	if s.Max != nil {
+		b.Max = s.Max.Duration
+	}
+	if s.Multiplier != 0 {
+		b.Multiplier = s.Multiplier
+	}
+	if s.Jitter != "" {
+		b.Jitter = s.Jitter
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []time.Duration
+	}{
+		{StrategyLinear, []time.Duration{1, 2, 3, 4, 5, 6}},
+		{StrategyExponential, []time.Duration{1, 2, 4, 8, 10, 10}},
+		{StrategyFibonacci, []time.Duration{1, 1, 2, 3, 5, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			b := NewBackoff(tt.strategy, time.Second, 10*time.Second)
+			for i, want := range tt.want {
+				if got := b.Delay(i+1, 0); got != want*time.Second {
+					t.Errorf("Delay(%d) = %s, want %s", i+1, got, want*time.Second)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := NewBackoff(StrategyExponential, time.Second, time.Minute)
+	b.Rand = func() float64 { return 0.5 }
+
+	b.Jitter = JitterFull
+	if got := b.Delay(3, 0); got != 2*time.Second {
+		t.Errorf("full jitter Delay(3) = %s, want 2s (half of 4s)", got)
+	}
+
+	b.Jitter = JitterDecorrelated
+	// upper bound is max(4s, 3*10s) = 30s, so the delay is 1s + 0.5*29s
+	if got := b.Delay(3, 10*time.Second); got != 15500*time.Millisecond {
+		t.Errorf("decorrelated Delay(3, 10s) = %s, want 15.5s", got)
+	}
+	b.Rand = func() float64 { return 0.999 }
+	if got := b.Delay(10, time.Hour); got > time.Minute {
+		t.Errorf("decorrelated delay %s exceeds max", got)
+	}
+}
+
+func TestLoadBackoffPoliciesOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backoff.yaml")
+	err := os.WriteFile(path, []byte(`
+default:
+  strategy: exponential
+  multiplier: 3
+kinds:
+  HelmRelease:
+    strategy: fibonacci
+    base: 1m
+namespaces:
+  sandbox:
+    strategy: linear
+    max: 6h
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := LoadBackoffPolicies(path, Backoff{Strategy: StrategyLinear, Base: 30 * time.Second, Max: time.Hour, Jitter: JitterNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := types.ResourceRef{Kind: types.FluxResourceKindKustomization, Namespace: "flux-system"}
+	hr := types.ResourceRef{Kind: types.FluxResourceKindHelmRelease, Namespace: "flux-system"}
+	sandboxHR := types.ResourceRef{Kind: types.FluxResourceKindHelmRelease, Namespace: "sandbox"}
+
+	if got := policies.Delay(ks, 2, 0); got != 90*time.Second {
+		t.Errorf("default Delay(2) = %s, want 90s (30s*3)", got)
+	}
+	if got := policies.Delay(hr, 4, 0); got != 3*time.Minute {
+		t.Errorf("kind override Delay(4) = %s, want 3m", got)
+	}
+	// namespace beats kind and inherits the default's 30s base
+	if got := policies.Delay(sandboxHR, 4, 0); got != 2*time.Minute {
+		t.Errorf("namespace override Delay(4) = %s, want 2m", got)
+	}
+
+	if _, err := LoadBackoffPolicies(path, Backoff{Strategy: "random", Base: time.Second, Max: time.Minute}); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
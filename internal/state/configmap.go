@@ -0,0 +1,541 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// ConfigMapStore defaults.
+const (
+	// DefaultShardBytes keeps each ConfigMap well below the 1MiB object limit,
+	// leaving room for metadata and managedFields.
+	DefaultShardBytes   = 512 * 1024
+	DefaultConfigMapTTL = 24 * time.Hour
+)
+
+// Labels identifying state shards.
+const (
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	labelState     = "fluxbrain.io/state"
+)
+
+// ConfigMapStore persists backoff state in ConfigMaps, for clusters without
+// Redis or persistent volumes. Entries are spread over shards named
+// "<name>-<n>"; a fingerprint stays in the shard that first stored it and new
+// fingerprints go to the first shard with room. Writes use the shard's
+// resourceVersion and retry on conflicts. Entries whose backoff ended and that
+// were not seen for TTL (notified entries: NotifyRetention after the last
+// notification; silenced entries: until the silence ends; history:
+// HistoryRetention after the failure was last seen; transitions: for their
+// window) are garbage
+// collected when their shard is written.
+type ConfigMapStore struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	// ShardBytes is the payload size at which a new shard is started.
+	ShardBytes int
+	// TTL is how long an entry is kept after its backoff ended and it was last seen.
+	TTL time.Duration
+	// NotifyRetention keeps notification records this long after the last notification.
+	NotifyRetention time.Duration
+	// HistoryRetention keeps the occurrence history this long after the failure was last seen.
+	HistoryRetention time.Duration
+	// Timeout bounds each store call (0 = none).
+	Timeout time.Duration
+	// Now returns the current time; tests inject a fake clock.
+	Now func() time.Time
+	// Backoff selects the backoff policy per resource.
+	Backoff *BackoffPolicies
+}
+
+// configMapEntry is the JSON value stored per fingerprint.
+type configMapEntry struct {
+	Failures int            `json:"failures"`
+	NextTry  time.Time      `json:"nextTry"`
+	Backoff  time.Duration  `json:"backoff"`
+	LastSeen time.Time      `json:"lastSeen"`
+	Notify   NotifyState    `json:"notify"`
+	Silence  *Silence       `json:"silence,omitempty"`
+	History  *types.History `json:"history,omitempty"`
+	Open     *OpenFailure   `json:"open,omitempty"`
+	// Transitions of a resource entry are kept for TransitionWindow.
+	Transitions      []time.Time   `json:"transitions,omitempty"`
+	TransitionWindow time.Duration `json:"transitionWindow,omitempty"`
+}
+
+// NewConfigMapStore creates a store writing ConfigMaps "<name>-<n>" in namespace.
+func NewConfigMapStore(client kubernetes.Interface, namespace, name string, baseBackoff, maxBackoff time.Duration) *ConfigMapStore {
+	return &ConfigMapStore{
+		Client:           client,
+		Namespace:        namespace,
+		Name:             name,
+		ShardBytes:       DefaultShardBytes,
+		TTL:              DefaultConfigMapTTL,
+		NotifyRetention:  DefaultNotifyRetention,
+		HistoryRetention: DefaultHistoryRetention,
+		Timeout:          DefaultRedisTimeout,
+		Now:              time.Now,
+		Backoff:          defaultBackoff(baseBackoff, maxBackoff),
+	}
+}
+
+// Ping checks that the shards can be listed.
+func (s *ConfigMapStore) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.shards(ctx)
+	return err
+}
+
+// InBackoff returns true if the fingerprint is currently in backoff period.
+func (s *ConfigMapStore) InBackoff(ctx context.Context, fp string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return false, fmt.Errorf("configmap backoff lookup: %w", err)
+	}
+	shard := findShard(shards, fp)
+	if shard == nil {
+		return false, nil
+	}
+	e, err := decodeConfigMapEntry(shard.Data[fp])
+	if err != nil {
+		return false, fmt.Errorf("decode entry %s: %w", fp, err)
+	}
+	return s.now().Before(e.NextTry), nil
+}
+
+// RegisterFailure increments the failure count and extends the backoff.
+func (s *ConfigMapStore) RegisterFailure(ctx context.Context, fp string, ref types.ResourceRef) error {
+	return s.modify(ctx, fp, func(e *configMapEntry, now time.Time) bool {
+		e.Failures++
+		e.Backoff = s.Backoff.Delay(ref, e.Failures, e.Backoff)
+		e.NextTry = now.Add(e.Backoff)
+		e.LastSeen = now
+		return true
+	})
+}
+
+// RegisterSuccess clears the backoff and removes entries that were never notified.
+func (s *ConfigMapStore) RegisterSuccess(ctx context.Context, fp string) error {
+	return s.modify(ctx, fp, func(e *configMapEntry, _ time.Time) bool {
+		e.Failures, e.NextTry, e.Backoff = 0, time.Time{}, 0
+		return e.Notify.Notified() || e.Silence != nil || e.History != nil || e.Open != nil
+	})
+}
+
+// NotifyState returns the notification record of fp.
+func (s *ConfigMapStore) NotifyState(ctx context.Context, fp string) (NotifyState, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return NotifyState{}, fmt.Errorf("configmap notify state: %w", err)
+	}
+	shard := findShard(shards, fp)
+	if shard == nil {
+		return NotifyState{}, nil
+	}
+	e, err := decodeConfigMapEntry(shard.Data[fp])
+	if err != nil {
+		return NotifyState{}, fmt.Errorf("decode entry %s: %w", fp, err)
+	}
+	return e.Notify, nil
+}
+
+// RecordNotification stores the notification record of fp.
+func (s *ConfigMapStore) RecordNotification(ctx context.Context, fp string, n NotifyState) error {
+	return s.modify(ctx, fp, func(e *configMapEntry, now time.Time) bool {
+		e.Notify = n
+		e.LastSeen = now
+		return true
+	})
+}
+
+// Forget removes the fingerprint, keeping a running snooze and the history.
+func (s *ConfigMapStore) Forget(ctx context.Context, fp string) error {
+	return s.modify(ctx, fp, func(e *configMapEntry, now time.Time) bool {
+		kept := configMapEntry{History: e.History, LastSeen: now}
+		if e.Silence.outlivesResolve(now) {
+			kept.Silence = e.Silence
+		}
+		*e = kept
+		return e.Silence != nil || e.History != nil
+	})
+}
+
+// Silence stores a silence for fp.
+func (s *ConfigMapStore) Silence(ctx context.Context, fp string, silence Silence) error {
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+	return s.modify(ctx, fp, func(e *configMapEntry, now time.Time) bool {
+		e.Silence = &silence
+		e.LastSeen = now
+		return true
+	})
+}
+
+// Unsilence removes the silence of fp.
+func (s *ConfigMapStore) Unsilence(ctx context.Context, fp string) error {
+	return s.modify(ctx, fp, func(e *configMapEntry, _ time.Time) bool {
+		e.Silence = nil
+		return e.Notify.Notified() || e.Failures > 0 || e.History != nil || e.Open != nil
+	})
+}
+
+// SilenceOf returns the silence of fp, or nil.
+func (s *ConfigMapStore) SilenceOf(ctx context.Context, fp string) (*Silence, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("configmap silence lookup: %w", err)
+	}
+	shard := findShard(shards, fp)
+	if shard == nil {
+		return nil, nil
+	}
+	e, err := decodeConfigMapEntry(shard.Data[fp])
+	if err != nil {
+		return nil, fmt.Errorf("decode entry %s: %w", fp, err)
+	}
+	return e.Silence, nil
+}
+
+// Silences returns all silences that have not ended.
+func (s *ConfigMapStore) Silences(ctx context.Context) (map[string]Silence, error) {
+	now := s.now()
+	out := make(map[string]Silence)
+	err := s.each(ctx, func(fp string, e *configMapEntry) {
+		if !e.Silence.expired(now) {
+			out[fp] = *e.Silence
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("configmap silences: %w", err)
+	}
+	return out, nil
+}
+
+// MarkOpen records fp as an open failure.
+func (s *ConfigMapStore) MarkOpen(ctx context.Context, fp string, o OpenFailure) error {
+	return s.modify(ctx, fp, func(e *configMapEntry, now time.Time) bool {
+		e.Open = &o
+		e.LastSeen = now
+		return true
+	})
+}
+
+// OpenFailures returns the open failures by fingerprint.
+func (s *ConfigMapStore) OpenFailures(ctx context.Context) (map[string]OpenFailure, error) {
+	out := make(map[string]OpenFailure)
+	err := s.each(ctx, func(fp string, e *configMapEntry) {
+		if e.Open != nil {
+			out[fp] = *e.Open
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("configmap open failures: %w", err)
+	}
+	return out, nil
+}
+
+// each calls fn for every readable entry of all shards.
+func (s *ConfigMapStore) each(ctx context.Context, fn func(fp string, e *configMapEntry)) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cm := range shards {
+		for fp, raw := range cm.Data {
+			if e, err := decodeConfigMapEntry(raw); err == nil {
+				fn(fp, &e)
+			}
+		}
+	}
+	return nil
+}
+
+// Observe records that fp was seen and returns its history.
+func (s *ConfigMapStore) Observe(ctx context.Context, fp string, at time.Time, revision string, occurrence bool) (types.History, error) {
+	var h types.History
+	err := s.modify(ctx, fp, func(e *configMapEntry, now time.Time) bool {
+		if historyExpired(e.History, at, s.HistoryRetention) {
+			e.History = &types.History{}
+		}
+		observe(e.History, at, revision, occurrence)
+		e.LastSeen = now
+		h = *e.History
+		return true
+	})
+	return h, err
+}
+
+// RecordTransition adds a transition of key and returns the transitions within window.
+func (s *ConfigMapStore) RecordTransition(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	var n int
+	err := s.modify(ctx, key, func(e *configMapEntry, now time.Time) bool {
+		e.Transitions = pruneTransitions(append(e.Transitions, at), at, window)
+		e.TransitionWindow = window
+		e.LastSeen = now
+		n = len(e.Transitions)
+		return true
+	})
+	return n, err
+}
+
+// Transitions returns the transitions of key within window before at.
+func (s *ConfigMapStore) Transitions(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("configmap transitions: %w", err)
+	}
+	shard := findShard(shards, key)
+	if shard == nil {
+		return 0, nil
+	}
+	e, err := decodeConfigMapEntry(shard.Data[key])
+	if err != nil {
+		return 0, fmt.Errorf("decode entry %s: %w", key, err)
+	}
+	return countTransitions(e.Transitions, at, window), nil
+}
+
+// Reset deletes all shards.
+func (s *ConfigMapStore) Reset(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	shards, err := s.shards(ctx)
+	if err != nil {
+		return fmt.Errorf("configmap reset: %w", err)
+	}
+	for _, cm := range shards {
+		err := s.Client.CoreV1().ConfigMaps(s.Namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("configmap reset: %w", err)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of fingerprints in backoff or with a notification record.
+func (s *ConfigMapStore) Len(ctx context.Context) (int, error) {
+	now := s.now()
+	n := 0
+	err := s.each(ctx, func(_ string, e *configMapEntry) {
+		if counted(e.NextTry, e.Notify, now) {
+			n++
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("configmap len: %w", err)
+	}
+	return n, nil
+}
+
+// modify applies fn to the entry of fp; fn returns false to delete it.
+// The shard holding fp (or the first one with room) is updated with its
+// resourceVersion, and the whole read-modify-write is retried on conflict.
+func (s *ConfigMapStore) modify(ctx context.Context, fp string, fn func(e *configMapEntry, now time.Time) bool) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		shards, err := s.shards(ctx)
+		if err != nil {
+			return err
+		}
+		now := s.now()
+
+		shard := findShard(shards, fp)
+		var e configMapEntry
+		if shard != nil {
+			decoded, err := decodeConfigMapEntry(shard.Data[fp])
+			if err == nil {
+				e = decoded
+			}
+		}
+		keep := fn(&e, now)
+		if shard == nil && !keep {
+			return nil
+		}
+
+		var value string
+		if keep {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			value = string(data)
+		}
+		if shard == nil {
+			shard = s.shardWithRoom(shards, len(fp)+len(value))
+		}
+		if shard == nil {
+			return s.createShard(ctx, nextShardIndex(s.Name, shards), fp, value)
+		}
+
+		cm := shard.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		if keep {
+			cm.Data[fp] = value
+		} else {
+			delete(cm.Data, fp)
+		}
+		s.collectGarbage(cm, now)
+		_, err = s.Client.CoreV1().ConfigMaps(s.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("configmap state update: %w", err)
+	}
+	return nil
+}
+
+// collectGarbage drops expired and unreadable entries from cm.
+func (s *ConfigMapStore) collectGarbage(cm *corev1.ConfigMap, now time.Time) {
+	for fp, raw := range cm.Data {
+		e, err := decodeConfigMapEntry(raw)
+		if err != nil || (now.After(e.NextTry) && now.Sub(e.LastSeen) > s.TTL && e.Notify.expired(now, s.NotifyRetention) && e.Silence.expired(now) && historyExpired(e.History, now, s.HistoryRetention) && transitionsExpired(e.Transitions, e.TransitionWindow, now)) {
+			delete(cm.Data, fp)
+		}
+	}
+}
+
+func (s *ConfigMapStore) createShard(ctx context.Context, index int, fp, value string) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name + "-" + strconv.Itoa(index),
+			Namespace: s.Namespace,
+			Labels:    s.labels(),
+		},
+		Data: map[string]string{fp: value},
+	}
+	_, err := s.Client.CoreV1().ConfigMaps(s.Namespace).Create(ctx, cm, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// another writer created the shard first; re-read like a conflict
+		return apierrors.NewConflict(corev1.Resource("configmaps"), cm.Name, err)
+	}
+	return err
+}
+
+// shards lists this store's ConfigMaps ordered by shard index.
+func (s *ConfigMapStore) shards(ctx context.Context) ([]corev1.ConfigMap, error) {
+	list, err := s.Client.CoreV1().ConfigMaps(s.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelState + "=" + s.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	shards := list.Items
+	sort.Slice(shards, func(i, j int) bool {
+		return shardIndex(s.Name, shards[i].Name) < shardIndex(s.Name, shards[j].Name)
+	})
+	return shards, nil
+}
+
+// shardWithRoom returns the first shard that can take size more bytes.
+func (s *ConfigMapStore) shardWithRoom(shards []corev1.ConfigMap, size int) *corev1.ConfigMap {
+	limit := s.ShardBytes
+	if limit <= 0 {
+		limit = DefaultShardBytes
+	}
+	for i := range shards {
+		if payloadSize(&shards[i])+size <= limit {
+			return &shards[i]
+		}
+	}
+	return nil
+}
+
+func (s *ConfigMapStore) labels() map[string]string {
+	return map[string]string{
+		labelManagedBy: "fluxbrain",
+		labelState:     s.Name,
+	}
+}
+
+func (s *ConfigMapStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
+
+func (s *ConfigMapStore) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+func findShard(shards []corev1.ConfigMap, fp string) *corev1.ConfigMap {
+	for i := range shards {
+		if _, ok := shards[i].Data[fp]; ok {
+			return &shards[i]
+		}
+	}
+	return nil
+}
+
+func payloadSize(cm *corev1.ConfigMap) int {
+	n := 0
+	for k, v := range cm.Data {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+// shardIndex parses n from "<name>-<n>"; unknown names sort last.
+func shardIndex(name, shard string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(shard, name+"-"))
+	if err != nil {
+		return math.MaxInt
+	}
+	return n
+}
+
+func nextShardIndex(name string, shards []corev1.ConfigMap) int {
+	next := 0
+	for _, cm := range shards {
+		if i := shardIndex(name, cm.Name); i >= next && i != math.MaxInt {
+			next = i + 1
+		}
+	}
+	return next
+}
+
+func decodeConfigMapEntry(raw string) (configMapEntry, error) {
+	var e configMapEntry
+	err := json.Unmarshal([]byte(raw), &e)
+	return e, err
+}
@@ -0,0 +1,129 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestConfigMapStore(client *fake.Clientset) *ConfigMapStore {
+	return NewConfigMapStore(client, "flux-system", "fluxbrain-state", time.Minute, time.Hour)
+}
+
+func TestConfigMapStoreBackoff(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := newTestConfigMapStore(client)
+	ctx := context.Background()
+
+	if inBackoff(t, store, "fp") {
+		t.Error("new fingerprint should not be in backoff")
+	}
+	mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+	if !inBackoff(t, store, "fp") {
+		t.Error("fingerprint should be in backoff after failure")
+	}
+
+	// a second store instance (restart, other CronJob pod) sees the same state
+	if !inBackoff(t, newTestConfigMapStore(client), "fp") {
+		t.Error("state should be shared through the ConfigMap")
+	}
+
+	mustDo(t, store.RegisterSuccess(ctx, "fp"))
+	if inBackoff(t, store, "fp") {
+		t.Error("fingerprint should be cleared after success")
+	}
+	if n, _ := store.Len(ctx); n != 0 {
+		t.Errorf("Len() = %d, want 0", n)
+	}
+}
+
+func TestConfigMapStoreShardsBySize(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := newTestConfigMapStore(client)
+	store.ShardBytes = 300
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		mustDo(t, store.RegisterFailure(ctx, fmt.Sprintf("fingerprint-%02d", i), testRef))
+	}
+
+	shards, err := store.shards(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) < 2 {
+		t.Fatalf("expected several shards, got %d", len(shards))
+	}
+	for _, cm := range shards {
+		if size := payloadSize(&cm); size > store.ShardBytes {
+			t.Errorf("shard %s has %d bytes, limit %d", cm.Name, size, store.ShardBytes)
+		}
+	}
+	if n, _ := store.Len(ctx); n != 10 {
+		t.Errorf("Len() = %d, want 10", n)
+	}
+
+	// updates stay in the shard that holds the fingerprint
+	mustDo(t, store.RegisterFailure(ctx, "fingerprint-00", testRef))
+	if n, _ := store.Len(ctx); n != 10 {
+		t.Errorf("Len() after update = %d, want 10", n)
+	}
+
+	mustDo(t, store.Reset(ctx))
+	if shards, _ := store.shards(ctx); len(shards) != 0 {
+		t.Errorf("Reset left %d shards", len(shards))
+	}
+}
+
+func TestConfigMapStoreRetriesConflicts(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := newTestConfigMapStore(client)
+	ctx := context.Background()
+	mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+
+	conflicts := 2
+	client.PrependReactor("update", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			conflicts--
+			return true, nil, apierrors.NewConflict(corev1.Resource("configmaps"), "fluxbrain-state-0", fmt.Errorf("stale resourceVersion"))
+		}
+		return false, nil, nil
+	})
+
+	mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+	if conflicts != 0 {
+		t.Fatalf("expected both conflicts to be consumed, %d left", conflicts)
+	}
+	shards, _ := store.shards(ctx)
+	e, err := decodeConfigMapEntry(shards[0].Data["fp"])
+	if err != nil || e.Failures != 2 {
+		t.Fatalf("entry = %+v, %v; want 2 failures", e, err)
+	}
+}
+
+func TestConfigMapStoreCollectsExpiredEntries(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := newTestConfigMapStore(client)
+	store.TTL = 30 * time.Minute
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	mustDo(t, store.RegisterFailure(ctx, "old", testRef))
+	now = now.Add(time.Hour)
+	mustDo(t, store.RegisterFailure(ctx, "fresh", testRef))
+
+	if n, _ := store.Len(ctx); n != 1 {
+		t.Errorf("Len() = %d, want expired entry to be collected", n)
+	}
+	if inBackoff(t, store, "old") {
+		t.Error("collected entry should not be in backoff")
+	}
+}
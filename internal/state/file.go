@@ -0,0 +1,409 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// FileStore defaults.
+const (
+	DefaultFileRetention       = 24 * time.Hour
+	DefaultFileCompactInterval = time.Hour
+)
+
+var fileBucket = []byte("fingerprints")
+
+// ResultRecorder is implemented by stores that keep the last notified
+// AnalysisResult per fingerprint.
+type ResultRecorder interface {
+	RecordResult(ctx context.Context, fp string, result types.AnalysisResult) error
+}
+
+// FileEntry is the record a FileStore keeps per fingerprint.
+type FileEntry struct {
+	Failures  int            `json:"failures"`
+	NextTry   time.Time      `json:"nextTry"`
+	Backoff   time.Duration  `json:"backoff"`
+	FirstSeen time.Time      `json:"firstSeen"`
+	LastSeen  time.Time      `json:"lastSeen"`
+	Notify    NotifyState    `json:"notify"`
+	Silence   *Silence       `json:"silence,omitempty"`
+	History   *types.History `json:"history,omitempty"`
+	Open      *OpenFailure   `json:"open,omitempty"`
+	// Transitions of a resource entry are kept for TransitionWindow.
+	Transitions      []time.Time           `json:"transitions,omitempty"`
+	TransitionWindow time.Duration         `json:"transitionWindow,omitempty"`
+	LastResult       *types.AnalysisResult `json:"lastResult,omitempty"`
+}
+
+// FileStore persists backoff state in an embedded bbolt database, e.g. on a
+// PVC, so restarts and once-mode runs keep their state. Entries survive
+// RegisterSuccess and Forget and are compacted once they were not seen for
+// Retention, their notification record is older than NotifyRetention and
+// their history older than HistoryRetention.
+type FileStore struct {
+	// Retention is how long an entry is kept after it was last seen and its backoff ended.
+	Retention time.Duration
+	// NotifyRetention keeps notification records this long after the last notification.
+	NotifyRetention time.Duration
+	// HistoryRetention keeps the occurrence history this long after the failure was last seen.
+	HistoryRetention time.Duration
+	// CompactInterval is the minimum time between automatic compactions.
+	CompactInterval time.Duration
+	// Now returns the current time; tests inject a fake clock.
+	Now func() time.Time
+	// Backoff selects the backoff policy per resource.
+	Backoff *BackoffPolicies
+
+	db *bolt.DB
+
+	mu          sync.Mutex
+	lastCompact time.Time
+}
+
+// OpenFileStore opens or creates the database at path and compacts expired entries.
+// Only one process may hold the file; a second opener fails after a short timeout.
+func OpenFileStore(path string, baseBackoff, maxBackoff time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create state directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open state file %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(fileBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init state file %s: %w", path, err)
+	}
+
+	s := &FileStore{
+		Retention:        DefaultFileRetention,
+		NotifyRetention:  DefaultNotifyRetention,
+		HistoryRetention: DefaultHistoryRetention,
+		CompactInterval:  DefaultFileCompactInterval,
+		Now:              time.Now,
+		Backoff:          defaultBackoff(baseBackoff, maxBackoff),
+		db:               db,
+	}
+	if _, err := s.Compact(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close releases the database file.
+func (s *FileStore) Close() error {
+	return s.db.Close()
+}
+
+// InBackoff returns true if the fingerprint is currently in backoff period.
+func (s *FileStore) InBackoff(_ context.Context, fp string) (bool, error) {
+	var in bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		e, err := getFileEntry(tx, fp)
+		if e != nil {
+			in = s.now().Before(e.NextTry)
+		}
+		return err
+	})
+	return in, err
+}
+
+// RegisterFailure increments the failure count and extends the backoff.
+func (s *FileStore) RegisterFailure(_ context.Context, fp string, ref types.ResourceRef) error {
+	return s.update(fp, func(e *FileEntry, now time.Time) {
+		e.Failures++
+		e.Backoff = s.Backoff.Delay(ref, e.Failures, e.Backoff)
+		e.NextTry = now.Add(e.Backoff)
+	})
+}
+
+// RegisterSuccess clears the backoff but keeps the entry's history.
+func (s *FileStore) RegisterSuccess(_ context.Context, fp string) error {
+	return s.update(fp, func(e *FileEntry, _ time.Time) {
+		e.Failures = 0
+		e.NextTry = time.Time{}
+		e.Backoff = 0
+	})
+}
+
+// NotifyState returns the notification record of fp.
+func (s *FileStore) NotifyState(ctx context.Context, fp string) (NotifyState, error) {
+	e, err := s.Entry(ctx, fp)
+	if e == nil || err != nil {
+		return NotifyState{}, err
+	}
+	return e.Notify, nil
+}
+
+// RecordNotification stores the notification record of fp.
+func (s *FileStore) RecordNotification(_ context.Context, fp string, n NotifyState) error {
+	return s.update(fp, func(e *FileEntry, _ time.Time) {
+		e.Notify = n
+	})
+}
+
+// Forget clears backoff, notification record, open record and acknowledgements of a
+// resolved failure but keeps a running snooze and the entry's history.
+func (s *FileStore) Forget(_ context.Context, fp string) error {
+	return s.update(fp, func(e *FileEntry, now time.Time) {
+		e.Failures = 0
+		e.NextTry = time.Time{}
+		e.Backoff = 0
+		e.Notify = NotifyState{}
+		e.Open = nil
+		if !e.Silence.outlivesResolve(now) {
+			e.Silence = nil
+		}
+	})
+}
+
+// Silence stores a silence for fp.
+func (s *FileStore) Silence(_ context.Context, fp string, silence Silence) error {
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+	return s.update(fp, func(e *FileEntry, _ time.Time) {
+		e.Silence = &silence
+	})
+}
+
+// Unsilence removes the silence of fp.
+func (s *FileStore) Unsilence(_ context.Context, fp string) error {
+	return s.update(fp, func(e *FileEntry, _ time.Time) {
+		e.Silence = nil
+	})
+}
+
+// SilenceOf returns the silence of fp, or nil.
+func (s *FileStore) SilenceOf(ctx context.Context, fp string) (*Silence, error) {
+	e, err := s.Entry(ctx, fp)
+	if e == nil || err != nil {
+		return nil, err
+	}
+	return e.Silence, nil
+}
+
+// Silences returns all silences that have not ended.
+func (s *FileStore) Silences(context.Context) (map[string]Silence, error) {
+	now := s.now()
+	out := make(map[string]Silence)
+	err := s.each(func(fp string, e *FileEntry) {
+		if !e.Silence.expired(now) {
+			out[fp] = *e.Silence
+		}
+	})
+	return out, err
+}
+
+// MarkOpen records fp as an open failure.
+func (s *FileStore) MarkOpen(_ context.Context, fp string, o OpenFailure) error {
+	return s.update(fp, func(e *FileEntry, _ time.Time) {
+		e.Open = &o
+	})
+}
+
+// OpenFailures returns the open failures by fingerprint.
+func (s *FileStore) OpenFailures(context.Context) (map[string]OpenFailure, error) {
+	out := make(map[string]OpenFailure)
+	err := s.each(func(fp string, e *FileEntry) {
+		if e.Open != nil {
+			out[fp] = *e.Open
+		}
+	})
+	return out, err
+}
+
+// each calls fn for every readable entry.
+func (s *FileStore) each(fn func(fp string, e *FileEntry)) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(fileBucket).ForEach(func(k, v []byte) error {
+			var e FileEntry
+			if err := json.Unmarshal(v, &e); err == nil {
+				fn(string(k), &e)
+			}
+			return nil
+		})
+	})
+}
+
+// Observe records that fp was seen and returns its history.
+func (s *FileStore) Observe(_ context.Context, fp string, at time.Time, revision string, occurrence bool) (types.History, error) {
+	var h types.History
+	err := s.update(fp, func(e *FileEntry, _ time.Time) {
+		if historyExpired(e.History, at, s.HistoryRetention) {
+			e.History = &types.History{}
+		}
+		observe(e.History, at, revision, occurrence)
+		h = *e.History
+	})
+	return h, err
+}
+
+// RecordTransition adds a transition of key and returns the transitions within window.
+func (s *FileStore) RecordTransition(_ context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	var n int
+	err := s.update(key, func(e *FileEntry, _ time.Time) {
+		e.Transitions = pruneTransitions(append(e.Transitions, at), at, window)
+		e.TransitionWindow = window
+		n = len(e.Transitions)
+	})
+	return n, err
+}
+
+// Transitions returns the transitions of key within window before at.
+func (s *FileStore) Transitions(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	e, err := s.Entry(ctx, key)
+	if e == nil || err != nil {
+		return 0, err
+	}
+	return countTransitions(e.Transitions, at, window), nil
+}
+
+// RecordResult stores the last notified AnalysisResult of fp.
+func (s *FileStore) RecordResult(_ context.Context, fp string, result types.AnalysisResult) error {
+	return s.update(fp, func(e *FileEntry, _ time.Time) {
+		e.LastResult = &result
+	})
+}
+
+// Entry returns the stored record of fp, or nil.
+func (s *FileStore) Entry(_ context.Context, fp string) (*FileEntry, error) {
+	var out *FileEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		out, err = getFileEntry(tx, fp)
+		return err
+	})
+	return out, err
+}
+
+// Reset clears all entries.
+func (s *FileStore) Reset(context.Context) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(fileBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(fileBucket)
+		return err
+	})
+}
+
+// Len returns the number of fingerprints in backoff or with a notification record.
+func (s *FileStore) Len(context.Context) (int, error) {
+	now := s.now()
+	n := 0
+	err := s.each(func(_ string, e *FileEntry) {
+		if counted(e.NextTry, e.Notify, now) {
+			n++
+		}
+	})
+	return n, err
+}
+
+// Compact deletes entries whose backoff ended, that were not seen for
+// Retention and whose notification record, silence, history and transitions
+// expired.
+// It returns the number of deleted entries.
+func (s *FileStore) Compact(context.Context) (int, error) {
+	now := s.now()
+	s.mu.Lock()
+	s.lastCompact = now
+	s.mu.Unlock()
+
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(fileBucket)
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var e FileEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				expired = append(expired, k) // unreadable entries are dropped
+				return nil
+			}
+			if now.After(e.NextTry) && now.Sub(e.LastSeen) > s.Retention && e.Notify.expired(now, s.NotifyRetention) && e.Silence.expired(now) && historyExpired(e.History, now, s.HistoryRetention) && transitionsExpired(e.Transitions, e.TransitionWindow, now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(expired)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("compact state file: %w", err)
+	}
+	return deleted, nil
+}
+
+// update applies fn to the entry of fp (creating it) and compacts when due.
+func (s *FileStore) update(fp string, fn func(e *FileEntry, now time.Time)) error {
+	now := s.now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		e, err := getFileEntry(tx, fp)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			e = &FileEntry{FirstSeen: now}
+		}
+		e.LastSeen = now
+		fn(e, now)
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(fileBucket).Put([]byte(fp), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	due := s.CompactInterval > 0 && now.Sub(s.lastCompact) >= s.CompactInterval
+	s.mu.Unlock()
+	if due {
+		_, err = s.Compact(context.Background())
+	}
+	return err
+}
+
+func getFileEntry(tx *bolt.Tx, fp string) (*FileEntry, error) {
+	data := tx.Bucket(fileBucket).Get([]byte(fp))
+	if data == nil {
+		return nil, nil
+	}
+	var e FileEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("decode entry %s: %w", fp, err)
+	}
+	return &e, nil
+}
+
+func (s *FileStore) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestFileStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	ctx := context.Background()
+
+	store, err := OpenFileStore(path, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+	mustDo(t, store.RecordResult(ctx, "fp", types.AnalysisResult{Summary: "apply failed"}))
+	mustDo(t, store.RecordNotification(ctx, "fp", NotifyState{FirstNotified: time.Now(), LastNotified: time.Now()}))
+	mustDo(t, store.Close())
+
+	store, err = OpenFileStore(path, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if !inBackoff(t, store, "fp") {
+		t.Error("backoff should survive a restart")
+	}
+	e, err := store.Entry(ctx, "fp")
+	if err != nil || e == nil {
+		t.Fatalf("Entry() = %v, %v", e, err)
+	}
+	if e.Failures != 1 || e.FirstSeen.IsZero() || e.LastSeen.IsZero() || !e.Notify.Notified() {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e.LastResult == nil || e.LastResult.Summary != "apply failed" {
+		t.Errorf("last result not persisted: %+v", e.LastResult)
+	}
+}
+
+func TestFileStoreSuccessKeepsHistory(t *testing.T) {
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+	first, _ := store.Entry(ctx, "fp")
+	mustDo(t, store.RegisterSuccess(ctx, "fp"))
+
+	if inBackoff(t, store, "fp") {
+		t.Error("success should clear the backoff")
+	}
+	e, _ := store.Entry(ctx, "fp")
+	if e == nil || e.Failures != 0 || !e.FirstSeen.Equal(first.FirstSeen) {
+		t.Errorf("success should reset failures but keep first-seen: %+v", e)
+	}
+}
+
+func TestFileStoreCompactsExpiredEntries(t *testing.T) {
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.Now = func() time.Time { return now }
+	store.Retention = 30 * time.Minute
+
+	mustDo(t, store.RegisterFailure(ctx, "old", testRef)) // backoff 1m
+	for i := 0; i < 60; i++ {
+		mustDo(t, store.RegisterFailure(ctx, "backing-off", testRef)) // backoff capped at 1h
+	}
+	now = now.Add(45 * time.Minute)
+	mustDo(t, store.RegisterFailure(ctx, "fresh", testRef))
+
+	// "old" was last seen 45m ago and its backoff ended; "backing-off" is still in backoff
+	n, err := store.Compact(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Compact() = %d, %v; want 1", n, err)
+	}
+	if e, _ := store.Entry(ctx, "old"); e != nil {
+		t.Error("expired entry should be compacted")
+	}
+	if l, _ := store.Len(ctx); l != 2 {
+		t.Errorf("Len() = %d, want 2", l)
+	}
+}
@@ -4,29 +4,126 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
 
-// Fingerprint generates a deterministic hash for an ErrorContext to enable deduplication.
-// The fingerprint is based on resource identity, reason, and git revision.
-func Fingerprint(ec types.ErrorContext) string {
-	data, _ := json.Marshal(struct {
-		Cluster   string `json:"cluster"`
-		Namespace string `json:"namespace"`
-		Kind      string `json:"kind"`
-		Name      string `json:"name"`
-		Reason    string `json:"reason"`
-		GitRev    string `json:"gitRev"`
-	}{
+// Fingerprinter maps an ErrorContext to the key used for deduplication and backoff.
+type Fingerprinter interface {
+	Fingerprint(ec types.ErrorContext) string
+}
+
+// FingerprintOptions selects what identifies a failure.
+type FingerprintOptions struct {
+	// Revision includes the Git revision, so every commit starts a new failure.
+	Revision bool
+	// Message includes the normalized error message, so different errors with
+	// the same reason stay apart.
+	Message bool
+	// GroupBySource identifies failures by their source (ec.Git.Repository)
+	// instead of the resource, so all resources fed by one broken source
+	// collapse into a single failure. Resources without a source are unaffected.
+	GroupBySource bool
+}
+
+// DefaultFingerprintOptions matches Fingerprint.
+var DefaultFingerprintOptions = FingerprintOptions{Revision: true}
+
+// ParseFingerprintOptions parses a comma-separated list of "revision",
+// "message" and "source". An empty list disables all options.
+func ParseFingerprintOptions(s string) (FingerprintOptions, error) {
+	var opts FingerprintOptions
+	for _, field := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "":
+		case "revision":
+			opts.Revision = true
+		case "message":
+			opts.Message = true
+		case "source":
+			opts.GroupBySource = true
+		default:
+			return opts, fmt.Errorf("unknown fingerprint option %q (want revision, message or source)", field)
+		}
+	}
+	return opts, nil
+}
+
+// NewFingerprinter returns a Fingerprinter for opts.
+func NewFingerprinter(opts FingerprintOptions) Fingerprinter {
+	return opts
+}
+
+// Fingerprint implements Fingerprinter. The hashed document only gains
+// fields when options are set, so fingerprints of the default options are
+// identical to those of earlier versions.
+func (o FingerprintOptions) Fingerprint(ec types.ErrorContext) string {
+	key := fingerprintKey{
 		Cluster:   ec.Cluster,
 		Namespace: ec.Resource.Namespace,
 		Kind:      string(ec.Resource.Kind),
 		Name:      ec.Resource.Name,
 		Reason:    ec.Reason,
-		GitRev:    ec.Git.Revision,
-	})
+	}
+	if o.Revision {
+		key.GitRev = ec.Git.Revision
+	}
+	if o.Message {
+		key.Message = NormalizeMessage(ec.ErrorMsg)
+	}
+	if o.GroupBySource && ec.Git.Repository != "" {
+		key.Namespace, key.Kind, key.Name = "", "", ""
+		key.Source = ec.Git.Repository
+	}
 
+	data, _ := json.Marshal(key)
 	hash := sha256.Sum256(data)
 	return fmt.Sprintf("%x", hash[:16])
 }
+
+type fingerprintKey struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+	GitRev    string `json:"gitRev"`
+	Message   string `json:"message,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+// Fingerprint generates a deterministic hash for an ErrorContext to enable deduplication.
+// The fingerprint is based on resource identity, reason, and git revision.
+func Fingerprint(ec types.ErrorContext) string {
+	return DefaultFingerprintOptions.Fingerprint(ec)
+}
+
+// messageMasks replace volatile parts of error messages, in order. Timestamps
+// and UUIDs go first so their digits are not taken for hashes or IPs.
+var messageMasks = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`(?i)\[?\b(([0-9a-f]{1,4}:){7}[0-9a-f]{1,4}|([0-9a-f]{1,4}:)+:([0-9a-f]{1,4}(:[0-9a-f]{1,4})*)?)(\]:\d+|\])?`), "<ip>"},
+	{regexp.MustCompile(`(?i)\b(sha1|sha256|sha384|sha512):[0-9a-f]+\b`), "<hash>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{7,}\b`), "<hash>"},
+	{regexp.MustCompile(`(?i)\b(line|column|col)(\s+)\d+(:\d+)?`), "$1$2<n>"},
+	{regexp.MustCompile(`(\.[A-Za-z]+):\d+(:\d+)?\b`), "$1:<n>"},
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// NormalizeMessage masks UUIDs, timestamps, IP addresses, hashes and line
+// numbers in msg and collapses whitespace, so repeated occurrences of the
+// same error produce the same text.
+func NormalizeMessage(msg string) string {
+	for _, m := range messageMasks {
+		msg = m.re.ReplaceAllString(msg, m.repl)
+	}
+	return strings.TrimSpace(whitespace.ReplaceAllString(msg, " "))
+}
@@ -0,0 +1,122 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+var goldenContext = types.ErrorContext{
+	Cluster:  "prod",
+	Resource: types.ResourceRef{Kind: types.FluxResourceKindKustomization, Name: "app", Namespace: "default"},
+	Reason:   "ReconciliationFailed",
+	Git: types.GitContext{
+		Repository: "https://github.com/org/fleet",
+		Revision:   "main@sha1:0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c",
+		Path:       "./apps",
+	},
+	ErrorMsg: "Deployment/default/app dry-run failed (line 12): admission webhook 10.96.0.1:443 denied request 3f2c1a9e-8b7d-4c6e-9f0a-1b2c3d4e5f60 at 2024-05-01T12:00:00Z",
+}
+
+// The expected values are fixed: changing them re-keys every stored backoff
+// entry and re-sends every open alert after an upgrade.
+func TestFingerprintGolden(t *testing.T) {
+	cases := []struct {
+		name string
+		opts FingerprintOptions
+		want string
+	}{
+		{"default", DefaultFingerprintOptions, "206af8dfece97d3bceb41d928c08662a"},
+		{"none", FingerprintOptions{}, "4c7e04f4b006599eafe75caca1fd7676"},
+		{"message", FingerprintOptions{Message: true}, "62b68eb45b4f333f465520c81384a649"},
+		{"source", FingerprintOptions{GroupBySource: true}, "c578f4b63410ae5db9ecb6eee548fdf2"},
+		{"all", FingerprintOptions{Revision: true, Message: true, GroupBySource: true}, "d91442ea5e9753a0a38e2e8c612b3498"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NewFingerprinter(tc.opts).Fingerprint(goldenContext); got != tc.want {
+				t.Errorf("fingerprint = %s, want %s", got, tc.want)
+			}
+		})
+	}
+	if got := Fingerprint(goldenContext); got != cases[0].want {
+		t.Errorf("Fingerprint() = %s, want the default options' %s", got, cases[0].want)
+	}
+}
+
+func TestFingerprintOptions(t *testing.T) {
+	other := goldenContext
+	other.Git.Revision = "main@sha1:1111111111111111111111111111111111111111"
+	other.ErrorMsg = "Deployment/default/app dry-run failed (line 40): admission webhook 10.96.0.7:443 denied request 0a0b0c0d-1e1f-4a4b-8c8d-9e9f0a0b0c0d at 2024-05-02T08:30:00Z"
+
+	noRev := NewFingerprinter(FingerprintOptions{Message: true})
+	if noRev.Fingerprint(goldenContext) != noRev.Fingerprint(other) {
+		t.Error("new revision with the same normalized message should keep the fingerprint")
+	}
+
+	other.ErrorMsg = "HelmRelease/default/app install failed"
+	if noRev.Fingerprint(goldenContext) == noRev.Fingerprint(other) {
+		t.Error("different messages with the same reason should differ")
+	}
+
+	bySource := NewFingerprinter(FingerprintOptions{GroupBySource: true})
+	sibling := goldenContext
+	sibling.Resource.Name, sibling.Resource.Namespace = "db", "data"
+	if bySource.Fingerprint(goldenContext) != bySource.Fingerprint(sibling) {
+		t.Error("resources of the same source should share a fingerprint")
+	}
+	sibling.Git.Repository = ""
+	orphan := sibling
+	orphan.Resource.Name = "cache"
+	if bySource.Fingerprint(sibling) == bySource.Fingerprint(orphan) {
+		t.Error("resources without a source should keep their own fingerprint")
+	}
+}
+
+func TestParseFingerprintOptions(t *testing.T) {
+	opts, err := ParseFingerprintOptions(" revision, Message,source ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts != (FingerprintOptions{Revision: true, Message: true, GroupBySource: true}) {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if opts, _ := ParseFingerprintOptions(""); opts != (FingerprintOptions{}) {
+		t.Errorf("empty list should disable all options, got %+v", opts)
+	}
+	if _, err := ParseFingerprintOptions("revision,commit"); err == nil {
+		t.Error("expected error for unknown option")
+	}
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{
+			goldenContext.ErrorMsg,
+			"Deployment/default/app dry-run failed (line <n>): admission webhook <ip> denied request <uuid> at <time>",
+		},
+		{
+			"artifact sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 not found for revision main@sha1:0f1e2d3c",
+			"artifact <hash> not found for revision main@<hash>",
+		},
+		{
+			"dial tcp [fd00:10:96::1]:443: connect: connection refused",
+			"dial tcp <ip>: connect: connection refused",
+		},
+		{
+			"error converting YAML to JSON: yaml: line 7: mapping values are not allowed\n  in kustomization.yaml:7:3",
+			"error converting YAML to JSON: yaml: line <n>: mapping values are not allowed in kustomization.yaml:<n>",
+		},
+		{
+			"health check failed after 5m0.00123s: timeout 2024-05-01 12:00:00.123+02:00",
+			"health check failed after 5m0.00123s: timeout <time>",
+		},
+	}
+	for _, tc := range cases {
+		if got := NormalizeMessage(tc.in); got != tc.want {
+			t.Errorf("NormalizeMessage(%q)\n got %q\nwant %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// NotifyFlapping is the notification kind of a resource that started flapping.
+const NotifyFlapping = "flapping"
+
+// FlapPolicy classifies a resource as flapping when it changed between
+// failing and recovered at least Threshold times within Window.
+// Threshold 0 disables flapping detection.
+type FlapPolicy struct {
+	Window    time.Duration
+	Threshold int
+}
+
+// DefaultFlapPolicy treats six transitions per hour (three fail/recover cycles) as flapping.
+var DefaultFlapPolicy = FlapPolicy{Window: time.Hour, Threshold: 6}
+
+// Flapping reports whether transitions within Window exceed the threshold.
+func (p FlapPolicy) Flapping(transitions int) bool {
+	return p.Threshold > 0 && transitions >= p.Threshold
+}
+
+// ResourceKey identifies a resource independent of its failures. Stores keep
+// the resource's transitions and flapping notification record under this key;
+// it has the format of a fingerprint and never collides with one.
+func ResourceKey(cluster string, ref types.ResourceRef) string {
+	data, _ := json.Marshal(struct {
+		Scope     string `json:"scope"`
+		Cluster   string `json:"cluster"`
+		Namespace string `json:"namespace"`
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+	}{"resource", cluster, ref.Namespace, string(ref.Kind), ref.Name})
+	hash := sha256.Sum256(data)
+	return fmt.Sprintf("%x", hash[:16])
+}
+
+// pruneTransitions drops transitions older than window before at and returns
+// the rest.
+func pruneTransitions(ts []time.Time, at time.Time, window time.Duration) []time.Time {
+	cutoff := at.Add(-window)
+	kept := ts[:0]
+	for _, t := range ts {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// countTransitions returns the number of transitions within window before at.
+func countTransitions(ts []time.Time, at time.Time, window time.Duration) int {
+	cutoff := at.Add(-window)
+	n := 0
+	for _, t := range ts {
+		if t.After(cutoff) {
+			n++
+		}
+	}
+	return n
+}
+
+// transitionsExpired reports whether the newest transition left window.
+func transitionsExpired(ts []time.Time, window time.Duration, now time.Time) bool {
+	return len(ts) == 0 || now.Sub(ts[len(ts)-1]) > window
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestFlapPolicy(t *testing.T) {
+	p := FlapPolicy{Window: time.Hour, Threshold: 4}
+	if p.Flapping(3) || !p.Flapping(4) {
+		t.Error("threshold should be inclusive")
+	}
+	if (FlapPolicy{Window: time.Hour}).Flapping(100) {
+		t.Error("threshold 0 disables flapping detection")
+	}
+}
+
+func TestResourceKey(t *testing.T) {
+	ref := types.ResourceRef{Kind: types.FluxResourceKindKustomization, Namespace: "flux-system", Name: "apps"}
+	key := ResourceKey("prod", ref)
+	if len(key) != 32 || key != ResourceKey("prod", ref) {
+		t.Fatalf("ResourceKey() = %q, want a stable fingerprint-like key", key)
+	}
+	if key == ResourceKey("staging", ref) {
+		t.Error("resources of different clusters must not share a key")
+	}
+	if key == Fingerprint(types.ErrorContext{Cluster: "prod", Resource: ref}) {
+		t.Error("resource keys must not collide with fingerprints")
+	}
+}
+
+func TestStoresCountTransitions(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]Store{
+		"memory":    NewMemoryStore(time.Minute, time.Hour),
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": newTestConfigMapStore(fake.NewSimpleClientset()),
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			for i, at := range []time.Time{now.Add(-70 * time.Minute), now.Add(-30 * time.Minute), now.Add(-10 * time.Minute)} {
+				n, err := store.RecordTransition(ctx, "res", at, time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := min(i+1, 2); n != want {
+					t.Errorf("transition %d: count = %d, want %d", i, n, want)
+				}
+			}
+			if n, err := store.Transitions(ctx, "res", now, time.Hour); err != nil || n != 2 {
+				t.Errorf("Transitions() = %d, %v, want 2", n, err)
+			}
+			if n, _ := store.Transitions(ctx, "res", now.Add(45*time.Minute), time.Hour); n != 1 {
+				t.Errorf("transitions should leave the window, got %d", n)
+			}
+			if n, _ := store.Transitions(ctx, "other", now, time.Hour); n != 0 {
+				t.Errorf("unknown resources have no transitions, got %d", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"time"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// DefaultHistoryRetention is how long stores keep the occurrence
+// history of a fingerprint after it was last seen.
+const DefaultHistoryRetention = 7 * 24 * time.Hour
+
+// maxHistoryRevisions bounds the distinct revisions kept per fingerprint; the
+// oldest are dropped first.
+const maxHistoryRevisions = 20
+
+// observe records that a failure was seen at with revision. occurrence counts
+// a new occurrence, i.e. the failure was not open before.
+func observe(h *types.History, at time.Time, revision string, occurrence bool) {
+	if h.FirstSeen.IsZero() {
+		h.FirstSeen = at
+	}
+	if at.After(h.LastSeen) {
+		h.LastSeen = at
+	}
+	if occurrence || h.Occurrences == 0 {
+		h.Occurrences++
+	}
+	if revision == "" {
+		return
+	}
+	for _, r := range h.Revisions {
+		if r == revision {
+			return
+		}
+	}
+	h.Revisions = append(h.Revisions, revision)
+	if n := len(h.Revisions); n > maxHistoryRevisions {
+		h.Revisions = h.Revisions[n-maxHistoryRevisions:]
+	}
+}
+
+// historyExpired reports whether h is nil or was last seen longer than retention ago.
+func historyExpired(h *types.History, now time.Time, retention time.Duration) bool {
+	if h == nil {
+		return true
+	}
+	if retention <= 0 {
+		retention = DefaultHistoryRetention
+	}
+	return now.Sub(h.LastSeen) > retention
+}
+
+// copyHistory returns h with its own Revisions slice.
+func copyHistory(h types.History) types.History {
+	h.Revisions = append([]string(nil), h.Revisions...)
+	return h
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestObserve(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	var h types.History
+	observe(&h, start, "main@sha1:aaa", false)
+	observe(&h, start.Add(time.Minute), "main@sha1:aaa", false)
+	observe(&h, start.Add(2*time.Minute), "", false)
+	observe(&h, start.Add(time.Hour), "main@sha1:bbb", true)
+
+	if !h.FirstSeen.Equal(start) || !h.LastSeen.Equal(start.Add(time.Hour)) {
+		t.Errorf("seen = %v..%v", h.FirstSeen, h.LastSeen)
+	}
+	if h.Occurrences != 2 {
+		t.Errorf("occurrences = %d, want 2 (first sighting and one recurrence)", h.Occurrences)
+	}
+	if want := []string{"main@sha1:aaa", "main@sha1:bbb"}; !slices.Equal(h.Revisions, want) {
+		t.Errorf("revisions = %v, want %v", h.Revisions, want)
+	}
+
+	for i := 0; i < 2*maxHistoryRevisions; i++ {
+		observe(&h, start, strconv.Itoa(i), false)
+	}
+	if len(h.Revisions) != maxHistoryRevisions || h.Revisions[len(h.Revisions)-1] != strconv.Itoa(2*maxHistoryRevisions-1) {
+		t.Errorf("revisions should keep the newest %d, got %v", maxHistoryRevisions, h.Revisions)
+	}
+}
+
+func TestStoresKeepHistoryAcrossForget(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]Store{
+		"memory":    NewMemoryStore(time.Minute, time.Hour),
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": newTestConfigMapStore(fake.NewSimpleClientset()),
+	}
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Observe(ctx, "fp", now, "rev1", true); err != nil {
+				t.Fatal(err)
+			}
+			mustDo(t, store.RegisterSuccess(ctx, "fp"))
+			mustDo(t, store.Forget(ctx, "fp"))
+
+			h, err := store.Observe(ctx, "fp", now.Add(time.Hour), "rev2", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Occurrences != 2 || !h.FirstSeen.Equal(now) || !h.LastSeen.Equal(now.Add(time.Hour)) {
+				t.Errorf("history = %+v, want 2 occurrences since %v", h, now)
+			}
+			if want := []string{"rev1", "rev2"}; !slices.Equal(h.Revisions, want) {
+				t.Errorf("revisions = %v, want %v", h.Revisions, want)
+			}
+		})
+	}
+}
+
+func TestStoresResetExpiredHistories(t *testing.T) {
+	retention := 24 * time.Hour
+	memoryStore := NewMemoryStore(time.Minute, time.Hour)
+	memoryStore.HistoryRetention = retention
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	redisStore.HistoryRetention = retention
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+	fileStore.HistoryRetention = retention
+	configMapStore := newTestConfigMapStore(fake.NewSimpleClientset())
+	configMapStore.HistoryRetention = retention
+
+	stores := map[string]Store{
+		"memory":    memoryStore,
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": configMapStore,
+	}
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Observe(ctx, "fp", now, "rev1", true); err != nil {
+				t.Fatal(err)
+			}
+			mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+
+			later := now.Add(2 * retention)
+			h, err := store.Observe(ctx, "fp", later, "rev2", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Occurrences != 1 || !h.FirstSeen.Equal(later) || !slices.Equal(h.Revisions, []string{"rev2"}) {
+				t.Errorf("an expired history should start over, got %+v", h)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreExpiresHistory(t *testing.T) {
+	store := NewMemoryStore(time.Minute, time.Hour)
+	store.HistoryRetention = 24 * time.Hour
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, fp := range []string{"gone", "back"} {
+		if _, err := store.Observe(ctx, fp, now, "rev1", true); err != nil {
+			t.Fatal(err)
+		}
+		mustDo(t, store.Forget(ctx, fp))
+	}
+
+	later := now.Add(48 * time.Hour)
+	h, err := store.Observe(ctx, "back", later, "rev2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Occurrences != 1 || !h.FirstSeen.Equal(later) || !slices.Equal(h.Revisions, []string{"rev2"}) {
+		t.Errorf("an expired history should start over, got %+v", h)
+	}
+	if n := len(store.data); n != 1 {
+		t.Errorf("the expired entry should be swept, %d entries left", n)
+	}
+}
@@ -0,0 +1,32 @@
+package state
+
+import "github.com/afeldman/fluxbrain/pkg/types"
+
+// OpenFailure is a failure that was reported and has not resolved yet. Stores
+// keep it until Forget so once-mode runs, restarts and a new leader know which
+// failures to resolve; it expires together with the fingerprint's history.
+type OpenFailure struct {
+	// Context is the last reported context without events, logs and the
+	// fields the engine attaches.
+	Context types.ErrorContext `json:"context"`
+	// Source names the collector that reported the failure; empty for pushed failures.
+	Source   string `json:"source,omitempty"`
+	Notified bool   `json:"notified,omitempty"`
+}
+
+// NewOpenFailure returns the OpenFailure of ec with ec trimmed for storage.
+func NewOpenFailure(ec types.ErrorContext, source string, notified bool) OpenFailure {
+	ec.Events, ec.LogSnippets = nil, nil
+	ec.History, ec.Transitions, ec.Notification = nil, nil, nil
+	ec.Fingerprint = ""
+	return OpenFailure{Context: ec, Source: source, Notified: notified}
+}
+
+// Equal compares the fields that identify and describe an open failure; the
+// engine rewrites a record only when they change.
+func (o OpenFailure) Equal(other OpenFailure) bool {
+	a, b := o.Context, other.Context
+	return o.Source == other.Source && o.Notified == other.Notified &&
+		a.Cluster == b.Cluster && a.Resource == b.Resource && a.Reason == b.Reason &&
+		a.ErrorMsg == b.ErrorMsg && a.Git == b.Git
+}
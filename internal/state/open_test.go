@@ -0,0 +1,67 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+func TestNewOpenFailureTrimsContext(t *testing.T) {
+	ec := types.ErrorContext{
+		Resource:    types.ResourceRef{Kind: "Kustomization", Name: "apps"},
+		ErrorMsg:    "boom",
+		Events:      []string{"e"},
+		LogSnippets: []string{"log"},
+		History:     &types.History{Occurrences: 2},
+		Fingerprint: "fp",
+	}
+	o := NewOpenFailure(ec, "flux", true)
+	if o.Context.Events != nil || o.Context.LogSnippets != nil || o.Context.History != nil || o.Context.Fingerprint != "" {
+		t.Errorf("context not trimmed: %+v", o.Context)
+	}
+	if !o.Equal(NewOpenFailure(o.Context, "flux", true)) {
+		t.Error("trimmed fields must not affect Equal")
+	}
+	if o.Equal(NewOpenFailure(ec, "", true)) {
+		t.Error("a different source must not be equal")
+	}
+}
+
+func TestStoresKeepOpenFailuresUntilForget(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]Store{
+		"memory":    NewMemoryStore(time.Minute, time.Hour),
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": newTestConfigMapStore(fake.NewSimpleClientset()),
+	}
+	ctx := context.Background()
+	ref := types.ResourceRef{Kind: "Kustomization", Namespace: "flux-system", Name: "apps"}
+	open := NewOpenFailure(types.ErrorContext{Cluster: "prod", Resource: ref, ErrorMsg: "boom"}, "flux", true)
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			mustDo(t, store.MarkOpen(ctx, "fp", open))
+			mustDo(t, store.RegisterSuccess(ctx, "fp"))
+			all, err := store.OpenFailures(ctx)
+			if err != nil || len(all) != 1 || !all["fp"].Equal(open) {
+				t.Fatalf("OpenFailures() = %+v, %v", all, err)
+			}
+
+			mustDo(t, store.Forget(ctx, "fp"))
+			if all, _ := store.OpenFailures(ctx); len(all) != 0 {
+				t.Errorf("Forget should drop the open failure, left %+v", all)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
+)
+
+// DefaultRedisTimeout bounds every Redis round trip so an unreachable Redis
+// cannot block the notification pipeline.
+const DefaultRedisTimeout = 2 * time.Second
+
+// DefaultRedisRetention keeps a failure count after its backoff ended, so a
+// failure that returns soon continues its backoff sequence.
+const DefaultRedisRetention = time.Hour
+
+// redisTxRetries is how often RegisterFailure retries after a concurrent write.
+const redisTxRetries = 5
+
+// RedisStore keeps backoff state in Redis so it survives restarts and is shared
+// between replicas. Every call is bounded by Timeout. Errors are returned to
+// the caller; InBackoff then reports false (fail open).
+type RedisStore struct {
+	Client  *redis.Client
+	Timeout time.Duration
+	// Backoff selects the backoff policy per resource.
+	Backoff *BackoffPolicies
+	// Retention keeps the failure count this long after the backoff ended.
+	Retention time.Duration
+	// NotifyRetention keeps notification records this long after the last notification.
+	NotifyRetention time.Duration
+	// HistoryRetention keeps the occurrence history this long after the failure was last seen.
+	HistoryRetention time.Duration
+
+	prefix string // key prefix for namespacing
+}
+
+// redisEntry is the JSON value stored per fingerprint.
+type redisEntry struct {
+	Failures int            `json:"failures"`
+	NextTry  time.Time      `json:"nextTry"`
+	Backoff  time.Duration  `json:"backoff"`
+	Notify   NotifyState    `json:"notify"`
+	Silence  *Silence       `json:"silence,omitempty"`
+	History  *types.History `json:"history,omitempty"`
+	Open     *OpenFailure   `json:"open,omitempty"`
+	// Transitions of a resource entry are kept for TransitionWindow.
+	Transitions      []time.Time   `json:"transitions,omitempty"`
+	TransitionWindow time.Duration `json:"transitionWindow,omitempty"`
+}
+
+// NewRedisStore creates a Redis-backed store. Keys are "<prefix>:backoff:<fp>".
+func NewRedisStore(client *redis.Client, baseBackoff, maxBackoff time.Duration, prefix string) *RedisStore {
+	return &RedisStore{
+		Client:           client,
+		Timeout:          DefaultRedisTimeout,
+		Backoff:          defaultBackoff(baseBackoff, maxBackoff),
+		Retention:        DefaultRedisRetention,
+		NotifyRetention:  DefaultNotifyRetention,
+		HistoryRetention: DefaultHistoryRetention,
+		prefix:           prefix,
+	}
+}
+
+// Ping checks that Redis responds.
+func (r *RedisStore) Ping(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return r.Client.Ping(ctx).Err()
+}
+
+// Close closes the Redis client.
+func (r *RedisStore) Close() error {
+	return r.Client.Close()
+}
+
+// InBackoff returns true if the fingerprint is currently in backoff period.
+// On Redis errors it returns false together with the error.
+func (r *RedisStore) InBackoff(ctx context.Context, fp string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	e, err := r.get(ctx, r.Client, fp)
+	if err != nil {
+		return false, fmt.Errorf("redis backoff lookup: %w", err)
+	}
+	return e != nil && time.Now().Before(e.NextTry), nil
+}
+
+// RegisterFailure increments the failure count and extends the backoff.
+func (r *RedisStore) RegisterFailure(ctx context.Context, fp string, ref types.ResourceRef) error {
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		e.Failures++
+		e.Backoff = r.Backoff.Delay(ref, e.Failures, e.Backoff)
+		e.NextTry = time.Now().Add(e.Backoff)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("redis register failure: %w", err)
+	}
+	return nil
+}
+
+// RegisterSuccess clears the backoff and deletes entries that were never notified.
+func (r *RedisStore) RegisterSuccess(ctx context.Context, fp string) error {
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		e.Failures, e.NextTry, e.Backoff = 0, time.Time{}, 0
+		return e.Notify.Notified() || e.Silence != nil || e.History != nil || e.Open != nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis register success: %w", err)
+	}
+	return nil
+}
+
+// NotifyState returns the notification record of fp.
+func (r *RedisStore) NotifyState(ctx context.Context, fp string) (NotifyState, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	e, err := r.get(ctx, r.Client, fp)
+	if err != nil {
+		return NotifyState{}, fmt.Errorf("redis notify state: %w", err)
+	}
+	if e == nil {
+		return NotifyState{}, nil
+	}
+	return e.Notify, nil
+}
+
+// RecordNotification stores the notification record of fp.
+func (r *RedisStore) RecordNotification(ctx context.Context, fp string, n NotifyState) error {
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		e.Notify = n
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("redis record notification: %w", err)
+	}
+	return nil
+}
+
+// Forget deletes the fingerprint, keeping a running snooze and the history.
+func (r *RedisStore) Forget(ctx context.Context, fp string) error {
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		kept := redisEntry{History: e.History}
+		if e.Silence.outlivesResolve(time.Now()) {
+			kept.Silence = e.Silence
+		}
+		*e = kept
+		return e.Silence != nil || e.History != nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis forget: %w", err)
+	}
+	return nil
+}
+
+// Silence stores a silence for fp.
+func (r *RedisStore) Silence(ctx context.Context, fp string, s Silence) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		e.Silence = &s
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("redis silence: %w", err)
+	}
+	return nil
+}
+
+// Unsilence removes the silence of fp.
+func (r *RedisStore) Unsilence(ctx context.Context, fp string) error {
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		e.Silence = nil
+		return e.Notify.Notified() || e.Failures > 0 || e.History != nil || e.Open != nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis unsilence: %w", err)
+	}
+	return nil
+}
+
+// SilenceOf returns the silence of fp, or nil.
+func (r *RedisStore) SilenceOf(ctx context.Context, fp string) (*Silence, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	e, err := r.get(ctx, r.Client, fp)
+	if err != nil {
+		return nil, fmt.Errorf("redis silence lookup: %w", err)
+	}
+	if e == nil {
+		return nil, nil
+	}
+	return e.Silence, nil
+}
+
+// Silences returns all silences of this prefix that have not ended.
+func (r *RedisStore) Silences(ctx context.Context) (map[string]Silence, error) {
+	now := time.Now()
+	out := make(map[string]Silence)
+	err := r.each(ctx, func(fp string, e *redisEntry) {
+		if !e.Silence.expired(now) {
+			out[fp] = *e.Silence
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis silences: %w", err)
+	}
+	return out, nil
+}
+
+// MarkOpen records fp as an open failure.
+func (r *RedisStore) MarkOpen(ctx context.Context, fp string, o OpenFailure) error {
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		e.Open = &o
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("redis mark open: %w", err)
+	}
+	return nil
+}
+
+// OpenFailures returns the open failures of this prefix by fingerprint.
+func (r *RedisStore) OpenFailures(ctx context.Context) (map[string]OpenFailure, error) {
+	out := make(map[string]OpenFailure)
+	err := r.each(ctx, func(fp string, e *redisEntry) {
+		if e.Open != nil {
+			out[fp] = *e.Open
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis open failures: %w", err)
+	}
+	return out, nil
+}
+
+// each calls fn for every readable entry of this prefix.
+func (r *RedisStore) each(ctx context.Context, fn func(fp string, e *redisEntry)) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	keys, err := r.keys(ctx)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	values, err := r.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue // deleted since the scan
+		}
+		var e redisEntry
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			continue
+		}
+		fn(strings.TrimPrefix(keys[i], r.keyPrefix()), &e)
+	}
+	return nil
+}
+
+// Observe records that fp was seen and returns its history.
+func (r *RedisStore) Observe(ctx context.Context, fp string, at time.Time, revision string, occurrence bool) (types.History, error) {
+	var h types.History
+	err := r.update(ctx, fp, func(e *redisEntry) bool {
+		if historyExpired(e.History, at, r.HistoryRetention) {
+			e.History = &types.History{}
+		}
+		observe(e.History, at, revision, occurrence)
+		h = *e.History
+		return true
+	})
+	if err != nil {
+		return types.History{}, fmt.Errorf("redis observe: %w", err)
+	}
+	return h, nil
+}
+
+// RecordTransition adds a transition of key and returns the transitions within window.
+func (r *RedisStore) RecordTransition(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	var n int
+	err := r.update(ctx, key, func(e *redisEntry) bool {
+		e.Transitions = pruneTransitions(append(e.Transitions, at), at, window)
+		e.TransitionWindow = window
+		n = len(e.Transitions)
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis record transition: %w", err)
+	}
+	return n, nil
+}
+
+// Transitions returns the transitions of key within window before at.
+func (r *RedisStore) Transitions(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	e, err := r.get(ctx, r.Client, key)
+	if err != nil {
+		return 0, fmt.Errorf("redis transitions: %w", err)
+	}
+	if e == nil {
+		return 0, nil
+	}
+	return countTransitions(e.Transitions, at, window), nil
+}
+
+// update applies fn to the entry of fp; fn returns false to delete it. The
+// read-modify-write runs in a WATCH transaction so concurrent replicas do not
+// lose writes.
+func (r *RedisStore) update(ctx context.Context, fp string, fn func(e *redisEntry) bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	key := r.key(fp)
+	txf := func(tx *redis.Tx) error {
+		e, err := r.get(ctx, tx, fp)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			e = &redisEntry{}
+		}
+		if !fn(e) {
+			_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+				p.Del(ctx, key)
+				return nil
+			})
+			return err
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, key, data, r.ttl(e))
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < redisTxRetries; i++ {
+		err := r.Client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("%w after %d attempts", redis.TxFailedErr, redisTxRetries)
+}
+
+// ttl keeps the failure count for Retention after the backoff ends, the
+// notification record for NotifyRetention after the last notification, the
+// history for HistoryRetention after the failure was last seen, transitions
+// for their window and a snooze until it ends. An acknowledgement is kept for
+// HistoryRetention after the entry was last written, which every cycle that
+// still sees the failure does; acknowledgements of failures that are gone
+// without resolving (e.g. an old revision) expire.
+func (r *RedisStore) ttl(e *redisEntry) time.Duration {
+	historyRetention := r.HistoryRetention
+	if historyRetention <= 0 {
+		historyRetention = DefaultHistoryRetention
+	}
+	ttl := time.Until(e.NextTry) + r.Retention
+	if e.Notify.Notified() {
+		retention := r.NotifyRetention
+		if retention <= 0 {
+			retention = DefaultNotifyRetention
+		}
+		if d := time.Until(e.Notify.LastNotified) + retention; d > ttl {
+			ttl = d
+		}
+	}
+	if e.History != nil {
+		if d := time.Until(e.History.LastSeen) + historyRetention; d > ttl {
+			ttl = d
+		}
+	}
+	if n := len(e.Transitions); n > 0 {
+		if d := time.Until(e.Transitions[n-1]) + e.TransitionWindow; d > ttl {
+			ttl = d
+		}
+	}
+	if e.Silence != nil {
+		d := time.Until(e.Silence.Until)
+		if e.Silence.Mode != SilenceSnooze {
+			d = historyRetention
+		}
+		if d > ttl {
+			ttl = d
+		}
+	}
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+	return ttl
+}
+
+// Reset deletes all backoff keys of this store's prefix. Keys of other
+// prefixes in the same database are left alone.
+func (r *RedisStore) Reset(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	keys, err := r.keys(ctx)
+	if err != nil {
+		return fmt.Errorf("redis reset: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := r.Client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("redis reset: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of fingerprints under this prefix that are in
+// backoff or have a notification record.
+func (r *RedisStore) Len(ctx context.Context) (int, error) {
+	now := time.Now()
+	n := 0
+	err := r.each(ctx, func(_ string, e *redisEntry) {
+		if counted(e.NextTry, e.Notify, now) {
+			n++
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis len: %w", err)
+	}
+	return n, nil
+}
+
+func (r *RedisStore) key(fp string) string {
+	return r.keyPrefix() + fp
+}
+
+func (r *RedisStore) keyPrefix() string {
+	return r.prefix + ":backoff:"
+}
+
+// keys scans for this store's keys. The prefix is escaped so glob characters
+// in it cannot match foreign keys.
+func (r *RedisStore) keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	iter := r.Client.Scan(ctx, 0, escapeGlob(r.keyPrefix())+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+func (r *RedisStore) get(ctx context.Context, c redis.Cmdable, fp string) (*redisEntry, error) {
+	data, err := c.Get(ctx, r.key(fp)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e redisEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("decode entry %s: %w", fp, err)
+	}
+	return &e, nil
+}
+
+func (r *RedisStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.Timeout)
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
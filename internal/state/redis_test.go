@@ -0,0 +1,131 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T, prefix string) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, time.Minute, time.Hour, prefix), mr
+}
+
+func TestRedisStoreBackoff(t *testing.T) {
+	store, mr := newTestRedisStore(t, "fluxbrain")
+	ctx := context.Background()
+	fp := "test-fingerprint"
+
+	if inBackoff(t, store, fp) {
+		t.Error("new fingerprint should not be in backoff")
+	}
+
+	mustDo(t, store.RegisterFailure(ctx, fp, testRef))
+	if !inBackoff(t, store, fp) {
+		t.Error("fingerprint should be in backoff after first failure")
+	}
+	if ttl := mr.TTL("fluxbrain:backoff:" + fp); ttl <= time.Minute {
+		t.Errorf("entry should outlive its backoff, ttl = %s", ttl)
+	}
+
+	mustDo(t, store.RegisterFailure(ctx, fp, testRef))
+	e, err := store.get(ctx, store.Client, fp)
+	if err != nil || e == nil || e.Failures != 2 {
+		t.Fatalf("entry = %+v, %v; want 2 failures", e, err)
+	}
+
+	mustDo(t, store.RegisterSuccess(ctx, fp))
+	if inBackoff(t, store, fp) {
+		t.Error("fingerprint should be cleared after success")
+	}
+}
+
+func TestRedisStoreConcurrentFailures(t *testing.T) {
+	store, _ := newTestRedisStore(t, "fluxbrain")
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.RegisterFailure(ctx, "fp", testRef); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	e, err := store.get(ctx, store.Client, "fp")
+	if err != nil || e == nil || e.Failures != 4 {
+		t.Fatalf("entry = %+v, %v; want 4 failures", e, err)
+	}
+}
+
+func TestRedisStoreResetOnlyOwnPrefix(t *testing.T) {
+	store, mr := newTestRedisStore(t, "team[a]")
+	ctx := context.Background()
+	mustDo(t, mr.Set("team[a]x:backoff:other", "{}"))
+	mustDo(t, mr.Set("teama:backoff:other", "{}"))
+	mustDo(t, mr.Set("unrelated", "keep"))
+
+	mustDo(t, store.RegisterFailure(ctx, "one", testRef))
+	mustDo(t, store.RegisterFailure(ctx, "two", testRef))
+	if n, err := store.Len(ctx); err != nil || n != 2 {
+		t.Fatalf("Len() = %d, %v; want 2", n, err)
+	}
+
+	mustDo(t, store.Reset(ctx))
+	if n, _ := store.Len(ctx); n != 0 {
+		t.Errorf("Len() after Reset = %d, want 0", n)
+	}
+	for _, key := range []string{"team[a]x:backoff:other", "teama:backoff:other", "unrelated"} {
+		if !mr.Exists(key) {
+			t.Errorf("Reset deleted foreign key %q", key)
+		}
+	}
+}
+
+func TestRedisStoreExpiresAcknowledgements(t *testing.T) {
+	store, mr := newTestRedisStore(t, "fluxbrain")
+	store.HistoryRetention = 24 * time.Hour
+	ctx := context.Background()
+	mustDo(t, store.Silence(ctx, "fp", Silence{Mode: SilenceRevision, Revision: "main@sha1:aaa"}))
+
+	if ttl := mr.TTL("fluxbrain:backoff:fp"); ttl <= 0 || ttl > 24*time.Hour {
+		t.Fatalf("acknowledgement ttl = %s, want HistoryRetention", ttl)
+	}
+	mr.FastForward(25 * time.Hour)
+	if s, err := store.SilenceOf(ctx, "fp"); err != nil || s != nil {
+		t.Errorf("stale acknowledgement should expire, got %+v, %v", s, err)
+	}
+}
+
+func TestRedisStoreFailsOpen(t *testing.T) {
+	store, mr := newTestRedisStore(t, "fluxbrain")
+	store.Timeout = 200 * time.Millisecond
+	ctx := context.Background()
+	mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+
+	mr.Close()
+	in, err := store.InBackoff(ctx, "fp")
+	if err == nil {
+		t.Fatal("expected an error with Redis down")
+	}
+	if in {
+		t.Error("InBackoff must fail open")
+	}
+	if err := store.RegisterFailure(ctx, "fp", testRef); err == nil {
+		t.Error("RegisterFailure should surface the error")
+	}
+	if err := store.Ping(ctx); err == nil {
+		t.Error("Ping should fail with Redis down")
+	}
+}
@@ -0,0 +1,131 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Notification kinds chosen by ReminderPolicy.
+const (
+	NotifyFirst      = "failure"
+	NotifyReminder   = "reminder"
+	NotifyEscalation = "escalation"
+)
+
+// DefaultNotifyRetention is how long stores keep the notification record of a
+// failure after its last notification when it is never resolved, e.g. because
+// the process restarted while the failure was open.
+const DefaultNotifyRetention = 7 * 24 * time.Hour
+
+// NotifyState is what a store remembers about the notifications of a fingerprint.
+// It is independent of the analyzer backoff and only cleared by Forget.
+type NotifyState struct {
+	FirstNotified time.Time `json:"firstNotified"`
+	LastNotified  time.Time `json:"lastNotified"`
+	// Reminders counts notifications after the first one.
+	Reminders int `json:"reminders"`
+}
+
+// Notified reports whether the fingerprint was notified before.
+func (n NotifyState) Notified() bool {
+	return !n.LastNotified.IsZero()
+}
+
+// expired reports whether the record is older than retention.
+func (n NotifyState) expired(now time.Time, retention time.Duration) bool {
+	if retention <= 0 {
+		retention = DefaultNotifyRetention
+	}
+	return now.Sub(n.LastNotified) > retention
+}
+
+// Next returns the record after a notification decided by d was sent at now.
+func (n NotifyState) Next(d NotifyDecision, now time.Time) NotifyState {
+	if !n.Notified() {
+		n.FirstNotified = now
+	}
+	n.LastNotified = now
+	n.Reminders = d.Reminder
+	return n
+}
+
+// NotifyDecision tells the engine whether and how to notify an open failure.
+type NotifyDecision struct {
+	Notify bool
+	// Kind is NotifyFirst, NotifyReminder or NotifyEscalation.
+	Kind string
+	// Reminder is the 1-based number of this reminder (0 for the first notification).
+	Reminder int
+}
+
+// ReminderPolicy decides when an open failure is notified again. A failure is
+// notified when it first occurs and suppressed while it persists unchanged.
+// Reminder n is sent Intervals[n-1] after the previous notification; the last
+// interval repeats. After EscalateAfter reminders every further reminder is an
+// escalation. Empty Intervals never remind; EscalateAfter 0 never escalates.
+type ReminderPolicy struct {
+	Intervals     []time.Duration
+	EscalateAfter int
+}
+
+// DefaultReminderPolicy reminds after 1h, 4h and then daily and escalates
+// from the fourth reminder on.
+var DefaultReminderPolicy = ReminderPolicy{
+	Intervals:     []time.Duration{time.Hour, 4 * time.Hour, 24 * time.Hour},
+	EscalateAfter: 3,
+}
+
+// ParseReminderIntervals parses a comma-separated list of durations such as "1h,4h,24h".
+func ParseReminderIntervals(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return nil, fmt.Errorf("reminder interval %q: %w", field, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("reminder interval %q must be positive", field)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// Decide returns what to do for a failure with notification record n at now.
+func (p ReminderPolicy) Decide(n NotifyState, now time.Time) NotifyDecision {
+	if !n.Notified() {
+		return NotifyDecision{Notify: true, Kind: NotifyFirst}
+	}
+	if len(p.Intervals) == 0 {
+		return NotifyDecision{}
+	}
+	i := n.Reminders
+	if i >= len(p.Intervals) {
+		i = len(p.Intervals) - 1
+	}
+	if now.Before(n.LastNotified.Add(p.Intervals[i])) {
+		return NotifyDecision{}
+	}
+	d := NotifyDecision{Notify: true, Kind: NotifyReminder, Reminder: n.Reminders + 1}
+	if p.EscalateAfter > 0 && n.Reminders >= p.EscalateAfter {
+		d.Kind = NotifyEscalation
+	}
+	return d
+}
+
+// Retention returns how long stores should keep notification records so no
+// reminder is lost: DefaultNotifyRetention or twice the longest interval.
+func (p ReminderPolicy) Retention() time.Duration {
+	retention := DefaultNotifyRetention
+	for _, d := range p.Intervals {
+		if 2*d > retention {
+			retention = 2 * d
+		}
+	}
+	return retention
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReminderPolicyDecide(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := ReminderPolicy{Intervals: []time.Duration{time.Hour, 4 * time.Hour, 24 * time.Hour}, EscalateAfter: 3}
+	sent := func(ago time.Duration, reminders int) NotifyState {
+		return NotifyState{FirstNotified: now.Add(-48 * time.Hour), LastNotified: now.Add(-ago), Reminders: reminders}
+	}
+
+	cases := []struct {
+		name  string
+		state NotifyState
+		want  NotifyDecision
+	}{
+		{"new failure", NotifyState{}, NotifyDecision{Notify: true, Kind: NotifyFirst}},
+		{"unchanged", sent(30*time.Minute, 0), NotifyDecision{}},
+		{"first reminder", sent(time.Hour, 0), NotifyDecision{Notify: true, Kind: NotifyReminder, Reminder: 1}},
+		{"second reminder not due", sent(3*time.Hour, 1), NotifyDecision{}},
+		{"second reminder", sent(4*time.Hour, 1), NotifyDecision{Notify: true, Kind: NotifyReminder, Reminder: 2}},
+		{"daily", sent(24*time.Hour, 2), NotifyDecision{Notify: true, Kind: NotifyReminder, Reminder: 3}},
+		{"escalation", sent(24*time.Hour, 3), NotifyDecision{Notify: true, Kind: NotifyEscalation, Reminder: 4}},
+		{"last interval repeats", sent(23*time.Hour, 7), NotifyDecision{}},
+	}
+	for _, tc := range cases {
+		if got := policy.Decide(tc.state, now); got != tc.want {
+			t.Errorf("%s: Decide() = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	if d := (ReminderPolicy{}).Decide(sent(1000*time.Hour, 0), now); d.Notify {
+		t.Error("a policy without intervals should never remind")
+	}
+}
+
+func TestNotifyStateNext(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := NotifyState{}.Next(NotifyDecision{Notify: true, Kind: NotifyFirst}, t0)
+	n = n.Next(NotifyDecision{Notify: true, Kind: NotifyReminder, Reminder: 1}, t0.Add(time.Hour))
+	if !n.FirstNotified.Equal(t0) || !n.LastNotified.Equal(t0.Add(time.Hour)) || n.Reminders != 1 {
+		t.Errorf("unexpected state %+v", n)
+	}
+}
+
+func TestParseReminderIntervals(t *testing.T) {
+	got, err := ParseReminderIntervals("1h, 4h,24h")
+	if err != nil || len(got) != 3 || got[2] != 24*time.Hour {
+		t.Fatalf("ParseReminderIntervals() = %v, %v", got, err)
+	}
+	if got, err := ParseReminderIntervals(""); err != nil || got != nil {
+		t.Errorf("empty list should disable reminders, got %v, %v", got, err)
+	}
+	for _, bad := range []string{"1h,soon", "0s"} {
+		if _, err := ParseReminderIntervals(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// Every backend keeps the notification record across RegisterSuccess, so an
+// open failure is not re-notified after each successful analysis, and drops
+// it on Forget.
+func TestStoresKeepNotifyStateUntilForget(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]Store{
+		"memory":    NewMemoryStore(time.Minute, time.Hour),
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": newTestConfigMapStore(fake.NewSimpleClientset()),
+	}
+	ctx := context.Background()
+	sent := NotifyState{FirstNotified: time.Now(), LastNotified: time.Now()}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			mustDo(t, store.RegisterFailure(ctx, "fp", testRef))
+			mustDo(t, store.RecordNotification(ctx, "fp", sent))
+			mustDo(t, store.RegisterSuccess(ctx, "fp"))
+
+			if inBackoff(t, store, "fp") {
+				t.Error("success should clear the backoff")
+			}
+			got, err := store.NotifyState(ctx, "fp")
+			if err != nil || !got.LastNotified.Equal(sent.LastNotified) {
+				t.Fatalf("NotifyState() = %+v, %v; want the recorded state", got, err)
+			}
+
+			mustDo(t, store.Forget(ctx, "fp"))
+			if got, _ := store.NotifyState(ctx, "fp"); got.Notified() {
+				t.Errorf("Forget should drop the notification record, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Silence modes.
+const (
+	// SilenceSnooze mutes a fingerprint until a point in time, even across resolution.
+	SilenceSnooze = "snooze"
+	// SilenceResolved acknowledges a failure until it resolves.
+	SilenceResolved = "resolved"
+	// SilenceRevision acknowledges a failure until its Git revision changes.
+	SilenceRevision = "revision"
+)
+
+// Silence mutes notifications of a fingerprint, e.g. for a resource that is
+// broken on purpose during a migration.
+type Silence struct {
+	Mode string `json:"mode"`
+	// Until ends a snooze.
+	Until time.Time `json:"until,omitempty"`
+	// Revision is the Git revision that was acknowledged.
+	Revision string    `json:"revision,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+// Validate checks mode and the fields it needs.
+func (s Silence) Validate() error {
+	switch s.Mode {
+	case SilenceSnooze:
+		if s.Until.IsZero() {
+			return errors.New("snooze needs an end time")
+		}
+	case SilenceResolved:
+	case SilenceRevision:
+		if s.Revision == "" {
+			return errors.New("revision acknowledgement needs the acknowledged revision")
+		}
+	default:
+		return fmt.Errorf("unknown silence mode %q (want %s, %s or %s)", s.Mode, SilenceSnooze, SilenceResolved, SilenceRevision)
+	}
+	return nil
+}
+
+// Active reports whether the silence mutes a failure seen at revision at now.
+func (s *Silence) Active(now time.Time, revision string) bool {
+	if s == nil {
+		return false
+	}
+	switch s.Mode {
+	case SilenceSnooze:
+		return now.Before(s.Until)
+	case SilenceRevision:
+		return revision == s.Revision
+	}
+	return true
+}
+
+// expired reports whether the silence can be dropped. Acknowledgements last
+// until Forget; snoozes until their end.
+func (s *Silence) expired(now time.Time) bool {
+	return s == nil || (s.Mode == SilenceSnooze && !now.Before(s.Until))
+}
+
+// outlivesResolve reports whether the silence is kept when its failure resolves.
+func (s *Silence) outlivesResolve(now time.Time) bool {
+	return s != nil && s.Mode == SilenceSnooze && !s.expired(now)
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSilenceActive(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		silence  *Silence
+		revision string
+		want     bool
+	}{
+		{"none", nil, "a", false},
+		{"snooze running", &Silence{Mode: SilenceSnooze, Until: now.Add(time.Minute)}, "a", true},
+		{"snooze ended", &Silence{Mode: SilenceSnooze, Until: now}, "a", false},
+		{"until resolved", &Silence{Mode: SilenceResolved}, "a", true},
+		{"same revision", &Silence{Mode: SilenceRevision, Revision: "a"}, "a", true},
+		{"new revision", &Silence{Mode: SilenceRevision, Revision: "a"}, "b", false},
+	}
+	for _, tc := range cases {
+		if got := tc.silence.Active(now, tc.revision); got != tc.want {
+			t.Errorf("%s: Active() = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+
+	for _, bad := range []Silence{{Mode: "forever"}, {Mode: SilenceSnooze}, {Mode: SilenceRevision}} {
+		if bad.Validate() == nil {
+			t.Errorf("expected %+v to be invalid", bad)
+		}
+	}
+}
+
+// Acknowledgements end with the failure, snoozes outlive it.
+func TestStoresSilences(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]Store{
+		"memory":    NewMemoryStore(time.Minute, time.Hour),
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": newTestConfigMapStore(fake.NewSimpleClientset()),
+	}
+	ctx := context.Background()
+	snooze := Silence{Mode: SilenceSnooze, Until: time.Now().Add(time.Hour).Truncate(time.Second), Reason: "migration"}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			mustDo(t, store.Silence(ctx, "acked", Silence{Mode: SilenceResolved}))
+			mustDo(t, store.Silence(ctx, "snoozed", snooze))
+			if err := store.Silence(ctx, "bad", Silence{Mode: SilenceSnooze}); err == nil {
+				t.Error("invalid silences must be rejected")
+			}
+
+			all, err := store.Silences(ctx)
+			if err != nil || len(all) != 2 || all["snoozed"].Reason != "migration" {
+				t.Fatalf("Silences() = %+v, %v", all, err)
+			}
+
+			mustDo(t, store.Forget(ctx, "acked"))
+			mustDo(t, store.Forget(ctx, "snoozed"))
+			if s, _ := store.SilenceOf(ctx, "acked"); s != nil {
+				t.Errorf("acknowledgement should end on resolution, got %+v", s)
+			}
+			if s, _ := store.SilenceOf(ctx, "snoozed"); s == nil || !s.Until.Equal(snooze.Until) {
+				t.Errorf("snooze should survive resolution, got %+v", s)
+			}
+
+			mustDo(t, store.Unsilence(ctx, "snoozed"))
+			if all, _ := store.Silences(ctx); len(all) != 0 {
+				t.Errorf("Unsilence should remove the snooze, left %+v", all)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package state
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"k8s.io/client-go/kubernetes/fake"
+
 	"github.com/afeldman/fluxbrain/pkg/types"
 )
 
@@ -64,28 +68,28 @@ func TestMemoryStoreBackoff(t *testing.T) {
 	store := NewMemoryStore(100*time.Millisecond, 1*time.Second)
 	fp := "test-fingerprint"
 
-	if store.InBackoff(fp) {
+	if inBackoff(t, store, fp) {
 		t.Error("new fingerprint should not be in backoff")
 	}
 
-	store.RegisterFailure(fp)
-	if !store.InBackoff(fp) {
+	mustDo(t, store.RegisterFailure(context.Background(), fp, testRef))
+	if !inBackoff(t, store, fp) {
 		t.Error("fingerprint should be in backoff after first failure")
 	}
 
 	time.Sleep(150 * time.Millisecond)
-	if store.InBackoff(fp) {
+	if inBackoff(t, store, fp) {
 		t.Error("fingerprint should exit backoff after delay")
 	}
 
-	store.RegisterFailure(fp)
-	store.RegisterFailure(fp)
-	if !store.InBackoff(fp) {
+	mustDo(t, store.RegisterFailure(context.Background(), fp, testRef))
+	mustDo(t, store.RegisterFailure(context.Background(), fp, testRef))
+	if !inBackoff(t, store, fp) {
 		t.Error("fingerprint should be in backoff after multiple failures")
 	}
 
-	store.RegisterSuccess(fp)
-	if store.InBackoff(fp) {
+	mustDo(t, store.RegisterSuccess(context.Background(), fp))
+	if inBackoff(t, store, fp) {
 		t.Error("fingerprint should be cleared after success")
 	}
 }
@@ -95,7 +99,7 @@ func TestMemoryStoreMaxBackoff(t *testing.T) {
 	fp := "test-max-backoff"
 
 	for i := 0; i < 20; i++ {
-		store.RegisterFailure(fp)
+		mustDo(t, store.RegisterFailure(context.Background(), fp, testRef))
 	}
 
 	store.mu.RLock()
@@ -107,3 +111,57 @@ func TestMemoryStoreMaxBackoff(t *testing.T) {
 		t.Errorf("backoff exceeded max: %v", backoff)
 	}
 }
+
+func inBackoff(t *testing.T, store Store, fp string) bool {
+	t.Helper()
+	in, err := store.InBackoff(context.Background(), fp)
+	if err != nil {
+		t.Fatalf("InBackoff(%s): %v", fp, err)
+	}
+	return in
+}
+
+func TestStoresCountOnlyActiveFingerprints(t *testing.T) {
+	redisStore, _ := newTestRedisStore(t, "fluxbrain")
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "state.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileStore.Close()
+
+	stores := map[string]interface {
+		Store
+		Counter
+	}{
+		"memory":    NewMemoryStore(time.Minute, time.Hour),
+		"redis":     redisStore,
+		"file":      fileStore,
+		"configmap": newTestConfigMapStore(fake.NewSimpleClientset()),
+	}
+	ctx := context.Background()
+	now := time.Now()
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			mustDo(t, store.RegisterFailure(ctx, "backoff", testRef))
+			mustDo(t, store.RecordNotification(ctx, "notified", NotifyState{FirstNotified: now, LastNotified: now}))
+			_, err := store.Observe(ctx, "history", now, "", true)
+			mustDo(t, err)
+			mustDo(t, store.Silence(ctx, "silenced", Silence{Mode: SilenceResolved}))
+			_, err = store.RecordTransition(ctx, ResourceKey("prod", testRef), now, time.Hour)
+			mustDo(t, err)
+
+			if n, err := store.Len(ctx); err != nil || n != 2 {
+				t.Errorf("Len() = %d, %v; want the fingerprints in backoff or notified", n, err)
+			}
+		})
+	}
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var testRef = types.ResourceRef{Kind: types.FluxResourceKindKustomization, Namespace: "flux-system", Name: "apps"}
@@ -1,61 +1,128 @@
 package state
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"github.com/afeldman/fluxbrain/pkg/types"
 )
 
 // Store manages backoff state for recurring errors to prevent notification spam.
+// Implementations backed by external services fail open: when the backend
+// errors, InBackoff reports false so a failure is notified rather than lost.
+//
+// Three independent records are kept per fingerprint: the analyzer backoff
+// (InBackoff, RegisterFailure, RegisterSuccess), the notification record used
+// by ReminderPolicy (NotifyState, RecordNotification), an optional Silence
+// and the OpenFailure record of a failure that has not resolved (MarkOpen).
+// Forget drops all of them except snoozes that have not ended. The occurrence
+// history of a fingerprint (Observe) survives Forget.
+//
+// Transitions between failing and recovered are kept per resource under
+// ResourceKey, together with the notification record of a flapping resource.
 type Store interface {
-	InBackoff(fp string) bool
-	RegisterFailure(fp string)
-	RegisterSuccess(fp string)
-	Reset()
+	InBackoff(ctx context.Context, fp string) (bool, error)
+	RegisterFailure(ctx context.Context, fp string, ref types.ResourceRef) error
+	// RegisterSuccess clears the analyzer backoff; the notification record is kept.
+	RegisterSuccess(ctx context.Context, fp string) error
+	// NotifyState returns the notification record; the zero value if fp was never notified.
+	NotifyState(ctx context.Context, fp string) (NotifyState, error)
+	RecordNotification(ctx context.Context, fp string, n NotifyState) error
+	// Forget removes the state of fp once its failure resolved.
+	Forget(ctx context.Context, fp string) error
+	// Silence stores s for fp, replacing an earlier silence.
+	Silence(ctx context.Context, fp string, s Silence) error
+	Unsilence(ctx context.Context, fp string) error
+	// SilenceOf returns the silence of fp, or nil.
+	SilenceOf(ctx context.Context, fp string) (*Silence, error)
+	// Silences returns all stored silences by fingerprint.
+	Silences(ctx context.Context) (map[string]Silence, error)
+	// MarkOpen records fp as an open failure until Forget.
+	MarkOpen(ctx context.Context, fp string, o OpenFailure) error
+	// OpenFailures returns the open failures by fingerprint.
+	OpenFailures(ctx context.Context) (map[string]OpenFailure, error)
+	// Observe records that fp was seen at with revision and returns its
+	// history; occurrence counts a new occurrence of the failure.
+	Observe(ctx context.Context, fp string, at time.Time, revision string, occurrence bool) (types.History, error)
+	// RecordTransition adds a transition of the resource key at and returns
+	// the number of transitions within window before at.
+	RecordTransition(ctx context.Context, key string, at time.Time, window time.Duration) (int, error)
+	// Transitions returns the number of transitions of key within window before at.
+	Transitions(ctx context.Context, key string, at time.Time, window time.Duration) (int, error)
+	Reset(ctx context.Context) error
+}
+
+// Pinger is implemented by stores backed by an external service so readiness
+// probes can verify the backend responds.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Counter is implemented by stores that can report how many fingerprints
+// currently hold back notifications, i.e. are in backoff or were notified.
+// Entries that only keep a history, a silence or transitions are not counted.
+type Counter interface {
+	Len(ctx context.Context) (int, error)
+}
+
+// counted reports whether an entry counts for Counter.Len at now.
+func counted(nextTry time.Time, n NotifyState, now time.Time) bool {
+	return now.Before(nextTry) || n.Notified()
 }
 
-// entry tracks failure count and next retry time for a fingerprint.
+// entry tracks failure count, next retry time and notifications for a fingerprint.
 type entry struct {
 	Failures int
 	NextTry  time.Time
+	Backoff  time.Duration
+	Notify   NotifyState
+	Silence  *Silence
+	History  *types.History
+	Open     *OpenFailure
+	// Transitions of a resource entry, oldest first.
+	Transitions []time.Time
 }
 
-// MemoryStore is an in-memory implementation of Store with exponential backoff.
+// memorySweepInterval is the minimum time between sweeps of a MemoryStore.
+const memorySweepInterval = time.Hour
+
+// MemoryStore is an in-memory implementation of Store. Observe sweeps
+// histories older than HistoryRetention, together with the open failure and
+// entries that keep nothing else.
 type MemoryStore struct {
-	mu          sync.RWMutex
-	data        map[string]*entry
-	baseBackoff time.Duration
-	maxBackoff  time.Duration
+	mu        sync.RWMutex
+	data      map[string]*entry
+	lastSweep time.Time
+	// Backoff selects the backoff policy per resource.
+	Backoff *BackoffPolicies
+	// HistoryRetention keeps the occurrence history this long after the failure was last seen.
+	HistoryRetention time.Duration
 }
 
 // NewMemoryStore creates a new in-memory state store.
 func NewMemoryStore(baseBackoff, maxBackoff time.Duration) *MemoryStore {
-	if baseBackoff == 0 {
-		baseBackoff = 30 * time.Second
-	}
-	if maxBackoff == 0 {
-		maxBackoff = 1 * time.Hour
-	}
 	return &MemoryStore{
-		data:        make(map[string]*entry),
-		baseBackoff: baseBackoff,
-		maxBackoff:  maxBackoff,
+		data:             make(map[string]*entry),
+		Backoff:          defaultBackoff(baseBackoff, maxBackoff),
+		HistoryRetention: DefaultHistoryRetention,
 	}
 }
 
 // InBackoff returns true if the fingerprint is currently in backoff period.
-func (s *MemoryStore) InBackoff(fp string) bool {
+func (s *MemoryStore) InBackoff(_ context.Context, fp string) (bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	e, ok := s.data[fp]
 	if !ok {
-		return false
+		return false, nil
 	}
-	return time.Now().Before(e.NextTry)
+	return time.Now().Before(e.NextTry), nil
 }
 
-// RegisterFailure increments the failure count and calculates next retry time with exponential backoff.
-func (s *MemoryStore) RegisterFailure(fp string) {
+// RegisterFailure increments the failure count and calculates the next retry time with ref's backoff policy.
+func (s *MemoryStore) RegisterFailure(_ context.Context, fp string, ref types.ResourceRef) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -66,98 +133,234 @@ func (s *MemoryStore) RegisterFailure(fp string) {
 	}
 
 	e.Failures++
-	backoff := time.Duration(e.Failures) * s.baseBackoff
-	if backoff > s.maxBackoff {
-		backoff = s.maxBackoff
+	e.Backoff = s.Backoff.Delay(ref, e.Failures, e.Backoff)
+	e.NextTry = time.Now().Add(e.Backoff)
+	return nil
+}
+
+// RegisterSuccess clears the backoff and drops entries that were never notified.
+func (s *MemoryStore) RegisterSuccess(_ context.Context, fp string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.data[fp]
+	if !ok {
+		return nil
+	}
+	if !e.Notify.Notified() && e.Silence == nil && e.History == nil && e.Open == nil {
+		delete(s.data, fp)
+		return nil
 	}
-	e.NextTry = time.Now().Add(backoff)
+	e.Failures, e.NextTry, e.Backoff = 0, time.Time{}, 0
+	return nil
 }
 
-// RegisterSuccess removes the fingerprint from backoff state (error resolved).
-func (s *MemoryStore) RegisterSuccess(fp string) {
+// NotifyState returns the notification record of fp.
+func (s *MemoryStore) NotifyState(_ context.Context, fp string) (NotifyState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if e, ok := s.data[fp]; ok {
+		return e.Notify, nil
+	}
+	return NotifyState{}, nil
+}
+
+// RecordNotification stores the notification record of fp.
+func (s *MemoryStore) RecordNotification(_ context.Context, fp string, n NotifyState) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.data, fp)
+	e := s.data[fp]
+	if e == nil {
+		e = &entry{}
+		s.data[fp] = e
+	}
+	e.Notify = n
+	return nil
 }
 
-// Reset clears all backoff state (useful for testing or forced reconciliation).
-func (s *MemoryStore) Reset() {
+// Forget removes the fingerprint (error resolved), keeping a running snooze
+// and the history.
+func (s *MemoryStore) Forget(_ context.Context, fp string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.data = make(map[string]*entry)
+	e, ok := s.data[fp]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	kept := &entry{}
+	if !historyExpired(e.History, now, s.HistoryRetention) {
+		kept.History = e.History
+	}
+	if e.Silence.outlivesResolve(now) {
+		kept.Silence = e.Silence
+	}
+	if kept.Silence == nil && kept.History == nil {
+		delete(s.data, fp)
+		return nil
+	}
+	s.data[fp] = kept
+	return nil
 }
 
-// RedisStore ist eine Redis-basierte Implementierung für Backoff-State
-// Achtung: Redis muss erreichbar sein, sonst blockiert die Notification-Logik!
-type RedisStore struct {
-	Client   *redis.Client
-	baseBackoff time.Duration
-	maxBackoff  time.Duration
-	prefix   string // Key-Prefix für Namespacing
+// Silence stores a silence for fp.
+func (s *MemoryStore) Silence(_ context.Context, fp string, silence Silence) error {
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.data[fp]
+	if e == nil {
+		e = &entry{}
+		s.data[fp] = e
+	}
+	e.Silence = &silence
+	return nil
 }
 
-// NewRedisStore initialisiert einen RedisStore
-func NewRedisStore(client *redis.Client, baseBackoff, maxBackoff time.Duration, prefix string) *RedisStore {
-	if baseBackoff == 0 {
-		baseBackoff = 30 * time.Second
+// Unsilence removes the silence of fp.
+func (s *MemoryStore) Unsilence(_ context.Context, fp string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.data[fp]
+	if !ok {
+		return nil
 	}
-	if maxBackoff == 0 {
-		maxBackoff = 1 * time.Hour
+	e.Silence = nil
+	if !e.Notify.Notified() && e.Failures == 0 && e.History == nil && e.Open == nil {
+		delete(s.data, fp)
 	}
-	return &RedisStore{
-		Client: client,
-		baseBackoff: baseBackoff,
-		maxBackoff:  maxBackoff,
-		prefix: prefix,
+	return nil
+}
+
+// SilenceOf returns the silence of fp, or nil.
+func (s *MemoryStore) SilenceOf(_ context.Context, fp string) (*Silence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if e, ok := s.data[fp]; ok && e.Silence != nil {
+		silence := *e.Silence
+		return &silence, nil
 	}
+	return nil, nil
 }
 
-func (r *RedisStore) key(fp string) string {
-	return r.prefix + ":backoff:" + fp
+// Silences returns all silences that have not ended.
+func (s *MemoryStore) Silences(context.Context) (map[string]Silence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	out := make(map[string]Silence)
+	for fp, e := range s.data {
+		if !e.Silence.expired(now) {
+			out[fp] = *e.Silence
+		}
+	}
+	return out, nil
 }
 
-func (r *RedisStore) InBackoff(fp string) bool {
-	key := r.key(fp)
-	val, err := r.Client.Get(r.Client.Context(), key).Result()
-	if err != nil {
-		return false
+// MarkOpen records fp as an open failure.
+func (s *MemoryStore) MarkOpen(_ context.Context, fp string, o OpenFailure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.data[fp]
+	if e == nil {
+		e = &entry{}
+		s.data[fp] = e
 	}
-	nextTry, err := time.Parse(time.RFC3339Nano, val)
-	if err != nil {
-		return false
+	e.Open = &o
+	return nil
+}
+
+// OpenFailures returns the open failures by fingerprint.
+func (s *MemoryStore) OpenFailures(context.Context) (map[string]OpenFailure, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]OpenFailure)
+	for fp, e := range s.data {
+		if e.Open != nil {
+			out[fp] = *e.Open
+		}
 	}
-	return time.Now().Before(nextTry)
+	return out, nil
 }
 
-func (r *RedisStore) RegisterFailure(fp string) {
-	key := r.key(fp)
-	// Hole aktuelle Anzahl Fehler
-	failKey := key + ":failures"
-	failures, _ := r.Client.Incr(r.Client.Context(), failKey).Result()
-	backoff := time.Duration(failures) * r.baseBackoff
-	if backoff > r.maxBackoff {
-		backoff = r.maxBackoff
+// Observe records that fp was seen and returns its history.
+func (s *MemoryStore) Observe(_ context.Context, fp string, at time.Time, revision string, occurrence bool) (types.History, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(at)
+	e := s.data[fp]
+	if e == nil {
+		e = &entry{}
+		s.data[fp] = e
+	}
+	if historyExpired(e.History, at, s.HistoryRetention) {
+		e.History = &types.History{}
 	}
-	nextTry := time.Now().Add(backoff)
-	r.Client.Set(r.Client.Context(), key, nextTry.Format(time.RFC3339Nano), backoff)
+	observe(e.History, at, revision, occurrence)
+	return copyHistory(*e.History), nil
 }
 
-func (r *RedisStore) RegisterSuccess(fp string) {
-	key := r.key(fp)
-	failKey := key + ":failures"
-	r.Client.Del(r.Client.Context(), key)
-	r.Client.Del(r.Client.Context(), failKey)
+// sweep drops expired histories with their open failure and deletes entries
+// that keep nothing else, at most once per memorySweepInterval.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for fp, e := range s.data {
+		if e.History == nil || !historyExpired(e.History, now, s.HistoryRetention) {
+			continue
+		}
+		e.History, e.Open = nil, nil
+		if e.Failures == 0 && !e.Notify.Notified() && e.Silence == nil && len(e.Transitions) == 0 {
+			delete(s.data, fp)
+		}
+	}
 }
 
-func (r *RedisStore) Reset() {
-	// Achtung: Löscht alle Keys mit Prefix
-	iter := r.Client.Scan(r.Client.Context(), 0, r.prefix+":backoff:*", 0).Iterator()
-	for iter.Next(r.Client.Context()) {
-		r.Client.Del(r.Client.Context(), iter.Val())
+// RecordTransition adds a transition of key and returns the transitions within window.
+func (s *MemoryStore) RecordTransition(_ context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.data[key]
+	if e == nil {
+		e = &entry{}
+		s.data[key] = e
 	}
-	iter = r.Client.Scan(r.Client.Context(), 0, r.prefix+":backoff:*:failures", 0).Iterator()
-	for iter.Next(r.Client.Context()) {
-		r.Client.Del(r.Client.Context(), iter.Val())
+	e.Transitions = pruneTransitions(append(e.Transitions, at), at, window)
+	return len(e.Transitions), nil
+}
+
+// Transitions returns the transitions of key within window before at.
+func (s *MemoryStore) Transitions(_ context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.data[key]
+	if !ok {
+		return 0, nil
 	}
+	return countTransitions(e.Transitions, at, window), nil
 }
+
+// Reset clears all backoff state (useful for testing or forced reconciliation).
+func (s *MemoryStore) Reset(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string]*entry)
+	return nil
+}
+
+// Len returns the number of fingerprints in backoff or with a notification record.
+func (s *MemoryStore) Len(context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	n := 0
+	for _, e := range s.data {
+		if counted(e.NextTry, e.Notify, now) {
+			n++
+		}
+	}
+	return n, nil
 }
@@ -9,9 +9,13 @@ import (
 type FluxResourceKind string
 
 const (
-	FluxResourceKindKustomization FluxResourceKind = "Kustomization"
-	FluxResourceKindHelmRelease   FluxResourceKind = "HelmRelease"
-	FluxResourceKindGitRepository FluxResourceKind = "GitRepository"
+	FluxResourceKindKustomization  FluxResourceKind = "Kustomization"
+	FluxResourceKindHelmRelease    FluxResourceKind = "HelmRelease"
+	FluxResourceKindGitRepository  FluxResourceKind = "GitRepository"
+	FluxResourceKindOCIRepository  FluxResourceKind = "OCIRepository"
+	FluxResourceKindHelmRepository FluxResourceKind = "HelmRepository"
+	FluxResourceKindHelmChart      FluxResourceKind = "HelmChart"
+	FluxResourceKindBucket         FluxResourceKind = "Bucket"
 )
 
 // ResourceRef identifies a Flux resource.
@@ -28,17 +32,81 @@ type GitContext struct {
 	Path       string `json:"path"`
 }
 
+// ChartContext captures the Helm chart behind a HelmRelease.
+type ChartContext struct {
+	Name                  string `json:"name"`
+	Version               string `json:"version"`
+	LastAttemptedRevision string `json:"lastAttemptedRevision,omitempty"`
+	InstallFailures       int64  `json:"installFailures"`
+	UpgradeFailures       int64  `json:"upgradeFailures"`
+}
+
+// ArtifactContext captures the last artifact a source-controller object stored.
+type ArtifactContext struct {
+	Revision       string    `json:"revision"`
+	Digest         string    `json:"digest,omitempty"`
+	URL            string    `json:"url,omitempty"`
+	LastUpdateTime time.Time `json:"lastUpdateTime"`
+}
+
+// Condition is an observed status condition of a Flux resource.
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
 // ErrorContext is the LLM-optimized context handed to analyzers.
 type ErrorContext struct {
-	Source      string      `json:"source"`
-	Cluster     string      `json:"cluster"`
-	Resource    ResourceRef `json:"resource"`
-	Git         GitContext  `json:"git"`
-	ErrorMsg    string      `json:"errorMsg"`
-	Reason      string      `json:"reason"`
-	Events      []string    `json:"events"`
-	LogSnippets []string    `json:"logSnippets"`
-	Timestamp   time.Time   `json:"timestamp"`
+	Source      string           `json:"source"`
+	Cluster     string           `json:"cluster"`
+	Resource    ResourceRef      `json:"resource"`
+	Git         GitContext       `json:"git"`
+	Chart       *ChartContext    `json:"chart,omitempty"`
+	Artifact    *ArtifactContext `json:"artifact,omitempty"`
+	Conditions  []Condition      `json:"conditions,omitempty"`
+	ErrorMsg    string           `json:"errorMsg"`
+	Reason      string           `json:"reason"`
+	Events      []string         `json:"events"`
+	LogSnippets []string         `json:"logSnippets"`
+	Timestamp   time.Time        `json:"timestamp"`
+	// History is what fluxbrain observed of this failure (same fingerprint) before.
+	History *History `json:"history,omitempty"`
+	// Transitions counts recent changes of the resource between failing and recovered.
+	Transitions *TransitionStats `json:"transitions,omitempty"`
+	// Notification is set on contexts handed to notifiers.
+	Notification *NotificationInfo `json:"notification,omitempty"`
+	// Fingerprint identifies the failure on contexts handed to notifiers.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// History summarizes the occurrences of a failure. An occurrence starts when
+// the failure is first reported after it was resolved (or never seen).
+type History struct {
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Occurrences int       `json:"occurrences"`
+	// Revisions lists the distinct Git revisions the failure was seen at, oldest first.
+	Revisions []string `json:"revisions,omitempty"`
+}
+
+// TransitionStats is how often a resource changed between failing and
+// recovered since Since, including the failure at hand.
+type TransitionStats struct {
+	Count int       `json:"count"`
+	Since time.Time `json:"since"`
+}
+
+// NotificationInfo says which notification of an open failure is sent.
+type NotificationInfo struct {
+	// Kind is "failure" for the first notification, "reminder", "escalation"
+	// or "flapping" for a resource that keeps failing and recovering.
+	Kind string `json:"kind"`
+	// Reminder is the 1-based number of the reminder (0 for the first notification).
+	Reminder      int       `json:"reminder,omitempty"`
+	FirstNotified time.Time `json:"firstNotified"`
 }
 
 // AnalysisResult is the normalized analysis output used downstream.
@@ -61,6 +129,12 @@ type Notifier interface {
 	Notify(ctx context.Context, ec ErrorContext, result AnalysisResult) error
 }
 
+// Resolver is implemented by notifiers that can report a failure as resolved,
+// e.g. by closing an issue or posting a recovery message.
+type Resolver interface {
+	Resolve(ctx context.Context, ec ErrorContext) error
+}
+
 // Collector gathers raw signals for a given resource.
 type Collector interface {
 	Collect(ctx context.Context, selector ResourceSelector) (CollectedSignals, error)
@@ -94,20 +168,23 @@ type LogSnippet struct {
 
 // ResourceStatus captures the latest observed status of a Flux resource.
 type ResourceStatus struct {
-	Kind                FluxResourceKind `json:"kind"`
-	Name                string           `json:"name"`
-	Namespace           string           `json:"namespace"`
-	Cluster             string           `json:"cluster"`
-	Ready               bool             `json:"ready"`
-	Status              string           `json:"status"`
-	Reason              string           `json:"reason"`
-	Message             string           `json:"message"`
-	LastHandledRevision string           `json:"lastHandledRevision"`
-	SourceRepository    string           `json:"sourceRepository"`
-	SourcePath          string           `json:"sourcePath"`
-	SourceRevision      string           `json:"sourceRevision"`
-	ReconciliationID    string           `json:"reconciliationId"`
-	ObservedAt          time.Time        `json:"observedAt"`
+	Kind                   FluxResourceKind `json:"kind"`
+	Name                   string           `json:"name"`
+	Namespace              string           `json:"namespace"`
+	Cluster                string           `json:"cluster"`
+	Ready                  bool             `json:"ready"`
+	Status                 string           `json:"status"`
+	Reason                 string           `json:"reason"`
+	Message                string           `json:"message"`
+	LastHandledRevision    string           `json:"lastHandledRevision"`
+	SourceRepository       string           `json:"sourceRepository"`
+	SourcePath             string           `json:"sourcePath"`
+	SourceRevision         string           `json:"sourceRevision"`
+	SourceRef              string           `json:"sourceRef"`
+	LastHandledReconcileAt string           `json:"lastHandledReconcileAt"`
+	ReconciliationID       string           `json:"reconciliationId"`
+	Conditions             []Condition      `json:"conditions"`
+	ObservedAt             time.Time        `json:"observedAt"`
 }
 
 // CollectedSignals bundles the raw data before context building.